@@ -2,6 +2,7 @@
 package cli
 
 import (
+	stdcontext "context"
 	"fmt"
 	"log"
 	"strings"
@@ -53,7 +54,11 @@ func (m UIMessage) RenderMessage(hideThoughts bool, isStreaming bool, spinnerVie
 		content := RenderMarkdown(msg.Content, hideThoughts, false, isStreaming, spinnerView)
 		return lipgloss.NewStyle().Foreground(lipgloss.Color(gray)).Render("> " + content)
 	case types.Assistant:
-		content := RenderMarkdown(msg.Content, hideThoughts, true, isStreaming, spinnerView)
+		text := msg.Content
+		if msg.Reasoning != "" {
+			text = "<think>" + msg.Reasoning + "</think>" + text
+		}
+		content := RenderMarkdown(text, hideThoughts, true, isStreaming, spinnerView)
 		return content
 	default:
 		return ""
@@ -135,6 +140,14 @@ type AppState[Context any] struct {
 	hideThoughts   bool
 	spinner        spinner.Model
 	context        *Context
+	// session persists the conversation across restarts when set.
+	session types.Session
+	// toolPreview shows a tool call while the model is still writing it.
+	toolPreview *CallAndResponse
+	// thinking is set while the model streams reasoning.
+	thinking bool
+	// approvals are the tool calls waiting for the user to allow them.
+	approvals []runner.ApprovalRequestedEvent
 }
 
 func (s *AppState[Context]) pushMessage(msg types.Message) {
@@ -199,14 +212,31 @@ func initialModel[Context any](agent *agents.Agent[Context], context *Context, c
 		FPS:    time.Second / 3,                //nolint:mnd
 	}
 
-	return AppState[Context]{
+	state := AppState[Context]{
 		components:   initialComponents(),
 		messages:     []types.Message{},
 		agent:        agent,
 		hideThoughts: config.HideThoughts,
 		spinner:      s,
 		context:      context,
+		session:      config.Session,
+	}
+	if state.session != nil {
+		history, err := state.session.Load(stdcontext.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, msg := range history {
+			state.pushMessage(msg)
+			for _, toolcall := range msg.ToolCalls {
+				state.items = append(state.items, MessageAreaItem{OfTool: &CallAndResponse{toolcall, ""}})
+			}
+			if msg.Role == types.Tool {
+				state.registerToolResponse(msg.ID, msg.Content)
+			}
+		}
 	}
+	return state
 }
 
 type (
@@ -227,6 +257,11 @@ func (s *AppState[Context]) ProcessCommand(userMessage string) bool {
 		s.responseBuffer = ""
 		s.items = []MessageAreaItem{}
 		s.messages = []types.Message{}
+		if s.session != nil {
+			if err := s.session.Clear(stdcontext.Background()); err != nil {
+				log.Fatal(err)
+			}
+		}
 		s.refreshViewport()
 	default:
 		return false
@@ -235,13 +270,57 @@ func (s *AppState[Context]) ProcessCommand(userMessage string) bool {
 }
 
 func (s AppState[Context]) OnEvent(event runner.AgentEvent) (tea.Model, tea.Cmd) {
+	// nested runs, such as agents used as tools or workflow steps, only
+	// surface their approval requests; their results arrive as tool
+	// results or the workflow's answer
+	if event.ParentToolCallID != "" || event.Step != "" {
+		if request, hasRequest := event.ApprovalRequested(); hasRequest {
+			s.approvals = append(s.approvals, *request)
+		}
+		return s, nil
+	}
+
+	// the buffer marks reasoning with <think> tags for rendering
+	if token, hasReasoning := event.ReasoningToken(); hasReasoning {
+		if !s.thinking {
+			s.responseBuffer += "<think>"
+			s.thinking = true
+		}
+		s.responseBuffer += token
+	}
+
 	if token, hasToken := event.Token(); hasToken {
+		if s.thinking {
+			s.responseBuffer += "</think>"
+			s.thinking = false
+		}
 		s.responseBuffer += token
 	}
 
+	// a retried request streams its answer again from the start
+	if _, hasRetry := event.Retry(); hasRetry {
+		s.responseBuffer = ""
+		s.thinking = false
+		s.toolPreview = nil
+	}
+
+	if delta, hasDelta := event.ToolCallDelta(); hasDelta {
+		args := ""
+		if preview := delta.PartialArgs(); len(preview) > 0 {
+			args = truncateWithEllipsis(utils.JsonDumps(preview, 0), 60)
+		}
+		s.toolPreview = &CallAndResponse{call: types.ToolCall{ID: delta.ToolCallID, Name: delta.Name, Args: args}}
+	}
+
+	if request, hasRequest := event.ApprovalRequested(); hasRequest {
+		s.approvals = append(s.approvals, *request)
+	}
+
 	if message, hasMessage := event.Message(); hasMessage {
 		s.pushMessage(*message)
 		s.responseBuffer = ""
+		s.thinking = false
+		s.toolPreview = nil
 
 		// handle tool calls
 		for _, toolcall := range message.ToolCalls {
@@ -286,6 +365,14 @@ func (s *AppState[Context]) refreshViewport() {
 		uiMessage := UIMessage{respMessage}
 		lines = append(lines, uiMessage.RenderMessage(s.hideThoughts, true, spinnerView)) // response buffer is streaming
 	}
+	if s.toolPreview != nil {
+		lines = append(lines, s.toolPreview.View())
+	}
+	if len(s.approvals) > 0 {
+		request := s.approvals[0]
+		prompt := fmt.Sprintf("Allow %s(%s)? [y/n]", lipgloss.NewStyle().Bold(true).Render(request.Name), truncateWithEllipsis(request.RawArgs, 60))
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color(ant)).Render(prompt))
+	}
 
 	content := strings.Join(lines, gap)
 	content = lipgloss.NewStyle().Width(vp.Width).Render(content)
@@ -298,12 +385,40 @@ func (s *AppState[Context]) GoToBottom() {
 	s.components.viewport.GotoBottom()
 }
 
+// resolveApproval answers the oldest pending approval with the pressed key
+// and reports whether the key was used.
+func (s *AppState[Context]) resolveApproval(key string) bool {
+	if len(s.approvals) == 0 || s.streamHandler.response == nil {
+		return false
+	}
+	request := s.approvals[0]
+	var err error
+	switch key {
+	case "y":
+		err = s.streamHandler.response.ResolveInRun(request.RunID, request.ToolCallID, runner.ApprovalDecision{Approved: true})
+	case "n":
+		err = s.streamHandler.response.ResolveInRun(request.RunID, request.ToolCallID, runner.ApprovalDecision{})
+	default:
+		return false
+	}
+	if err != nil {
+		s.agent.Logger.Error("failed to resolve approval", "tool_call_id", request.ToolCallID, "error", err)
+	}
+	s.approvals = s.approvals[1:]
+	s.GoToBottom()
+	return true
+}
+
 func (s AppState[Context]) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var (
 		tiCmd      tea.Cmd
 		vpCmd      tea.Cmd
 		spinnerCmd tea.Cmd
 	)
+	// while a tool call waits for approval, y and n answer it
+	if key, ok := msg.(tea.KeyMsg); ok && s.resolveApproval(key.String()) {
+		return s, nil
+	}
 	s.components.inputBox, tiCmd = s.components.inputBox.Update(msg)
 	s.components.viewport, vpCmd = s.components.viewport.Update(msg)
 	s.spinner, spinnerCmd = s.spinner.Update(msg)
@@ -315,6 +430,9 @@ func (s AppState[Context]) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return s, tea.Quit
 		case tea.KeyEsc:
 			s.streamHandler.Stop()
+			s.toolPreview = nil
+			s.thinking = false
+			s.approvals = nil
 			// add the current response buffer to conversation
 			if len(s.responseBuffer) > 0 {
 				respMessage := types.NewAssistantMessage(s.responseBuffer, s.agent.Name, []types.ToolCall{})
@@ -332,7 +450,13 @@ func (s AppState[Context]) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			s.components.inputBox.Reset()
 
 			// Initialize stream control
-			agentResponse := StreamAgent(s.agent, s.messages, s.context)
+			var agentResponse *runner.AgentResponse
+			if s.session != nil {
+				// the session supplies the history and records the new turn
+				agentResponse = StreamAgent(s.agent, []types.Message{userMessage}, s.context, runner.WithSession(s.session))
+			} else {
+				agentResponse = StreamAgent(s.agent, s.messages, s.context)
+			}
 			s.streamHandler.response = agentResponse
 
 			go func() {
@@ -404,6 +528,7 @@ var p *tea.Program
 type tuiConfig struct {
 	HideThoughts bool
 	LogToFile    string
+	Session      types.Session
 }
 
 func (c *tuiConfig) Apply(opts ...TUIOption) error {
@@ -443,6 +568,14 @@ func LogToFile(filename string) TUIOption {
 	})
 }
 
+// WithSession keeps the conversation in session, restoring it on start.
+func WithSession(session types.Session) TUIOption {
+	return tuiOptionFunc(func(config *tuiConfig) error {
+		config.Session = session
+		return nil
+	})
+}
+
 func RunTUI[Context any](agent *agents.Agent[Context], context *Context, opts ...TUIOption) {
 	config := DefaultTUIConfig()
 	config.Apply(opts...)
@@ -454,11 +587,11 @@ func RunTUI[Context any](agent *agents.Agent[Context], context *Context, opts ..
 	}
 }
 
-func StreamAgent[Context any](agent *agents.Agent[Context], messages []types.Message, context *Context) *runner.AgentResponse {
-	agentResponse, err := runner.Run(types.Agent[Context](*agent), runner.Input{OfMessages: messages}, context)
+func StreamAgent[Context any](agent *agents.Agent[Context], messages []types.Message, context *Context, opts ...runner.RunOption) *runner.AgentResponse {
+	agentResponse, err := runner.Run(stdcontext.Background(), types.Agent[Context](*agent), runner.Input{OfMessages: messages}, context, opts...)
 	if err != nil {
 		log.Fatal(err)
 		panic(err)
 	}
-	return &agentResponse
+	return agentResponse
 }
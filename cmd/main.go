@@ -6,7 +6,7 @@ import (
 	"github.com/logkn/agents-go/tools"
 )
 
-var agent = agents.BaseAgent(agents.NewModel("qwen3:30b-a3b", agents.WithBaseURL("http://localhost:11434/v1"))).WithBaseTools(tools.SearchTool)
+var agent = agents.BaseAgent(agents.NewModel("qwen3:30b-a3b", agents.WithProvider(agents.OllamaProvider()))).WithBaseTools(tools.SearchTool)
 
 func main() {
 	cli.RunTUI(agent, agents.Null, cli.LogToFile("logs.txt"))
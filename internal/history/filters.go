@@ -0,0 +1,65 @@
+package history
+
+import (
+	"context"
+
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// lastMessages keeps the n most recent messages.
+type lastMessages struct {
+	n int
+}
+
+// LastMessages keeps the n most recent messages, plus as many earlier ones
+// as needed not to separate a tool result from its call. Leading system
+// messages are always kept.
+func LastMessages(n int) types.HistoryStrategy {
+	return lastMessages{n: max(n, 1)}
+}
+
+func (s lastMessages) Compact(_ context.Context, messages []types.Message) ([]types.Message, error) {
+	pinned, rest := splitPinned(messages)
+	spans := units(rest)
+	start, kept := len(spans), 0
+	for start > 0 && kept < s.n {
+		start--
+		kept += len(spans[start])
+	}
+	if start == 0 {
+		return messages, nil
+	}
+
+	out := join(pinned, nil)
+	for _, span := range spans[start:] {
+		out = append(out, span...)
+	}
+	return out, nil
+}
+
+// dropToolCalls removes tool calls and their results.
+type dropToolCalls struct{}
+
+// DropToolCalls removes tool results and the tool calls of assistant
+// messages, keeping only what was said. Assistant messages left empty are
+// dropped.
+func DropToolCalls() types.HistoryStrategy {
+	return dropToolCalls{}
+}
+
+func (dropToolCalls) Compact(_ context.Context, messages []types.Message) ([]types.Message, error) {
+	out := make([]types.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role == types.Tool {
+			continue
+		}
+		if len(msg.ToolCalls) > 0 {
+			if msg.Content == "" {
+				continue
+			}
+			msg.ToolCalls = nil
+		}
+		out = append(out, msg)
+	}
+	return out, nil
+}
@@ -0,0 +1,133 @@
+// Package history provides the built-in strategies that keep a conversation
+// within a model's context window, which also serve as handoff input filters.
+package history
+
+import (
+	"context"
+
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// EstimateTokens roughly counts the tokens of messages, at four characters per
+// token plus a small overhead per message. It is meant for budgeting, not
+// billing.
+func EstimateTokens(messages []types.Message) int {
+	chars := 0
+	for _, msg := range messages {
+		chars += len(msg.Content) + len(msg.Name) + len(msg.Reasoning)
+		for _, call := range msg.ToolCalls {
+			chars += len(call.Name) + len(call.Args)
+		}
+	}
+	return chars/4 + 4*len(messages)
+}
+
+// units splits messages into the spans that must be kept or dropped together:
+// an assistant message with tool calls forms one span with the tool results
+// that follow it.
+func units(messages []types.Message) [][]types.Message {
+	spans := [][]types.Message{}
+	for i := 0; i < len(messages); {
+		end := i + 1
+		if len(messages[i].ToolCalls) > 0 {
+			for end < len(messages) && messages[end].Role == types.Tool {
+				end++
+			}
+		}
+		spans = append(spans, messages[i:end])
+		i = end
+	}
+	return spans
+}
+
+// splitPinned separates leading system messages, which are always kept, from
+// the rest of the conversation.
+func splitPinned(messages []types.Message) (pinned, rest []types.Message) {
+	i := 0
+	for i < len(messages) && messages[i].Role == types.System {
+		i++
+	}
+	return messages[:i], messages[i:]
+}
+
+// join returns a new slice holding pinned followed by kept.
+func join(pinned, kept []types.Message) []types.Message {
+	out := make([]types.Message, 0, len(pinned)+len(kept))
+	return append(append(out, pinned...), kept...)
+}
+
+// lastTurns keeps the conversation from the n-th last user message on.
+type lastTurns struct {
+	n int
+}
+
+// LastTurns keeps only the last n turns, a turn starting with each user
+// message. Leading system messages are always kept.
+func LastTurns(n int) types.HistoryStrategy {
+	return lastTurns{n: max(n, 1)}
+}
+
+func (s lastTurns) Compact(_ context.Context, messages []types.Message) ([]types.Message, error) {
+	pinned, rest := splitPinned(messages)
+	seen := 0
+	for i := len(rest) - 1; i >= 0; i-- {
+		if rest[i].Role != types.User {
+			continue
+		}
+		seen++
+		if seen == s.n {
+			return join(pinned, rest[i:]), nil
+		}
+	}
+	return messages, nil
+}
+
+// tokenBudget keeps the most recent messages fitting the budget.
+type tokenBudget struct {
+	maxTokens int
+}
+
+// TokenBudget keeps the most recent messages whose estimated size fits in
+// maxTokens. An assistant tool call is never separated from its results, and
+// the kept window starts at a user message whenever one fits. The latest
+// message is always kept, even if it alone exceeds the budget.
+func TokenBudget(maxTokens int) types.HistoryStrategy {
+	return tokenBudget{maxTokens: maxTokens}
+}
+
+func (s tokenBudget) Compact(_ context.Context, messages []types.Message) ([]types.Message, error) {
+	if EstimateTokens(messages) <= s.maxTokens {
+		return messages, nil
+	}
+	pinned, rest := splitPinned(messages)
+	return join(pinned, recentWithin(rest, s.maxTokens-EstimateTokens(pinned))), nil
+}
+
+// recentWithin returns the longest suffix of messages made of whole units
+// that fits in budget, trimmed to start at a user message if it contains one.
+func recentWithin(messages []types.Message, budget int) []types.Message {
+	spans := units(messages)
+	start, used := len(spans), 0
+	for start > 0 {
+		size := EstimateTokens(spans[start-1])
+		if used+size > budget && start < len(spans) {
+			break
+		}
+		used += size
+		start--
+	}
+
+	// some APIs require the conversation to open with the user
+	for i := start; i < len(spans); i++ {
+		if spans[i][0].Role == types.User {
+			start = i
+			break
+		}
+	}
+
+	kept := []types.Message{}
+	for _, span := range spans[start:] {
+		kept = append(kept, span...)
+	}
+	return kept
+}
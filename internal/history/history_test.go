@@ -0,0 +1,148 @@
+package history
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/logkn/agents-go/internal/types"
+)
+
+func conversation() []types.Message {
+	return []types.Message{
+		types.NewSystemMessage("be brief"),
+		types.NewUserMessage("look up the weather"),
+		types.NewAssistantMessage("", "agent", []types.ToolCall{{ID: "call_1", Name: "weather", Args: `{"city":"Oslo"}`}}),
+		types.NewToolMessage("call_1", strings.Repeat("sunny ", 40)),
+		types.NewAssistantMessage("It is sunny.", "agent", nil),
+		types.NewUserMessage("and tomorrow?"),
+		types.NewAssistantMessage("", "agent", []types.ToolCall{{ID: "call_2", Name: "weather", Args: `{"city":"Oslo","day":1}`}}),
+		types.NewToolMessage("call_2", "rain"),
+	}
+}
+
+func TestLastTurns(t *testing.T) {
+	messages := conversation()
+	kept, err := LastTurns(1).Compact(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(kept) != 4 || kept[0].Role != types.System || kept[1].Content != "and tomorrow?" {
+		t.Fatalf("expected the system prompt and last turn, got %+v", kept)
+	}
+
+	kept, _ = LastTurns(5).Compact(context.Background(), messages)
+	if len(kept) != len(messages) {
+		t.Fatalf("expected short conversations to be kept whole, got %d messages", len(kept))
+	}
+}
+
+func TestTokenBudgetKeepsToolResults(t *testing.T) {
+	messages := conversation()
+	budget := EstimateTokens(messages) - 10
+	kept, err := TokenBudget(budget).Compact(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if EstimateTokens(kept) > budget {
+		t.Fatalf("expected at most %d tokens, got %d", budget, EstimateTokens(kept))
+	}
+	if kept[0].Role != types.System || kept[1].Role != types.User {
+		t.Fatalf("expected the window to open with the system prompt and a user message, got %+v", kept[:2])
+	}
+	for i, msg := range kept {
+		if msg.Role == types.Tool && len(kept[i-1].ToolCalls) == 0 && kept[i-1].Role != types.Tool {
+			t.Fatalf("tool result %d was separated from its call", i)
+		}
+	}
+
+	// the latest exchange survives even a budget it does not fit
+	kept, _ = TokenBudget(1).Compact(context.Background(), messages)
+	if last := kept[len(kept)-1]; last.Content != "rain" || len(kept[len(kept)-2].ToolCalls) == 0 {
+		t.Fatalf("expected the last tool call and result to be kept, got %+v", kept)
+	}
+}
+
+func TestLastMessages(t *testing.T) {
+	kept, err := LastMessages(1).Compact(context.Background(), conversation())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(kept) != 3 || kept[0].Role != types.System || kept[1].ToolCalls[0].ID != "call_2" {
+		t.Fatalf("expected the system prompt and the last tool call with its result, got %+v", kept)
+	}
+}
+
+func TestDropToolCalls(t *testing.T) {
+	kept, err := DropToolCalls().Compact(context.Background(), conversation())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"be brief", "look up the weather", "It is sunny.", "and tomorrow?"}
+	if len(kept) != len(want) {
+		t.Fatalf("expected %d messages, got %+v", len(want), kept)
+	}
+	for i, msg := range kept {
+		if msg.Content != want[i] || len(msg.ToolCalls) > 0 {
+			t.Fatalf("unexpected message %d: %+v", i, msg)
+		}
+	}
+}
+
+// fakeProvider answers every request with a fixed summary.
+type fakeProvider struct {
+	requests []types.CompletionRequest
+}
+
+func (p *fakeProvider) Stream(_ context.Context, req types.CompletionRequest) (types.CompletionStream, error) {
+	p.requests = append(p.requests, req)
+	return &fakeStream{}, nil
+}
+
+type fakeStream struct{ done bool }
+
+func (s *fakeStream) Next() bool {
+	if s.done {
+		return false
+	}
+	s.done = true
+	return true
+}
+
+func (s *fakeStream) Current() types.CompletionChunk {
+	return types.CompletionChunk{Content: "the user asked about the weather in Oslo"}
+}
+func (s *fakeStream) Err() error   { return nil }
+func (s *fakeStream) Close() error { return nil }
+
+func TestSummarize(t *testing.T) {
+	provider := &fakeProvider{}
+	messages := conversation()
+	summarizer := Summarize(types.ModelConfig{Model: "fake", Provider: provider}, EstimateTokens(messages)-10)
+
+	kept, err := summarizer.Compact(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(provider.requests) != 1 {
+		t.Fatalf("expected one summary request, got %d", len(provider.requests))
+	}
+	if !strings.Contains(provider.requests[0].Messages[1].Content, "look up the weather") {
+		t.Fatalf("expected the older turns in the transcript, got %q", provider.requests[0].Messages[1].Content)
+	}
+	if kept[0].Role != types.System || !strings.HasPrefix(kept[1].Content, summaryHeader) {
+		t.Fatalf("expected the system prompt then the summary, got %+v", kept[:2])
+	}
+	if last := kept[len(kept)-1]; last.Content != "rain" {
+		t.Fatalf("expected recent messages kept verbatim, got %+v", last)
+	}
+
+	// the next turn reuses the summary instead of asking again
+	next := append(messages, types.NewAssistantMessage("Rain tomorrow.", "agent", nil))
+	if _, err := summarizer.Compact(context.Background(), next); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(provider.requests) != 1 {
+		t.Fatalf("expected the summary to be reused, got %d requests", len(provider.requests))
+	}
+}
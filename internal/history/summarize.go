@@ -0,0 +1,184 @@
+package history
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/logkn/agents-go/internal/providers"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+const (
+	summaryPrompt = "You compress conversations between a user and an AI assistant. " +
+		"Summarize the transcript you are given so the assistant can continue the work without it. " +
+		"Keep facts, decisions, file names, tool results that still matter and open tasks. " +
+		"Answer with the summary only."
+	summaryHeader = "Summary of the earlier conversation:\n\n"
+	// maxSummaries bounds how many summaries are remembered for reuse.
+	maxSummaries = 16
+)
+
+// summary is an LLM-written summary of the first covered messages of a
+// conversation, identified by their hash.
+type summary struct {
+	covered int
+	hash    [sha256.Size]byte
+	text    string
+}
+
+// Summarizer replaces older messages with an LLM-written summary once the
+// conversation grows past its token budget. Summaries are remembered, so
+// later turns reuse them until the conversation outgrows the budget again.
+// A Summarizer can be shared by concurrent runs.
+type Summarizer struct {
+	model     types.ModelConfig
+	maxTokens int
+
+	mu        sync.Mutex
+	summaries []summary
+}
+
+// Summarize returns a strategy that uses model to summarize the conversation
+// whenever it exceeds maxTokens, keeping the most recent messages, up to half
+// the budget, verbatim.
+func Summarize(model types.ModelConfig, maxTokens int) *Summarizer {
+	return &Summarizer{model: model, maxTokens: maxTokens}
+}
+
+func (s *Summarizer) Compact(ctx context.Context, messages []types.Message) ([]types.Message, error) {
+	if EstimateTokens(messages) <= s.maxTokens {
+		return messages, nil
+	}
+	pinned, rest := splitPinned(messages)
+
+	previous, found := s.lookup(rest)
+	if found {
+		view := join(pinned, withSummary(previous.text, rest[previous.covered:]))
+		if EstimateTokens(view) <= s.maxTokens {
+			return view, nil
+		}
+	}
+
+	recent := recentWithin(rest, s.maxTokens/2)
+	older := rest[:len(rest)-len(recent)]
+	if len(older) == 0 {
+		return messages, nil
+	}
+
+	// build on the previous summary rather than rereading what it covers
+	prior, unsummarized := "", older
+	if found && previous.covered <= len(older) {
+		prior, unsummarized = previous.text, older[previous.covered:]
+	}
+	text, err := s.summarize(ctx, prior, unsummarized)
+	if err != nil {
+		return nil, err
+	}
+	s.remember(summary{covered: len(older), hash: hashMessages(older), text: text})
+	return join(pinned, withSummary(text, recent)), nil
+}
+
+// withSummary prepends the summary to the recent messages.
+func withSummary(text string, recent []types.Message) []types.Message {
+	out := make([]types.Message, 0, len(recent)+1)
+	out = append(out, types.NewUserMessage(summaryHeader+text))
+	return append(out, recent...)
+}
+
+// summarize asks the model to fold messages into the prior summary.
+func (s *Summarizer) summarize(ctx context.Context, prior string, messages []types.Message) (string, error) {
+	var transcript strings.Builder
+	if prior != "" {
+		transcript.WriteString(summaryHeader + prior + "\n\nContinuation:\n\n")
+	}
+	for _, msg := range messages {
+		writeTranscriptLine(&transcript, msg)
+	}
+
+	stream, err := providers.For(s.model).Stream(ctx, types.CompletionRequest{
+		Model: s.model,
+		Messages: []types.Message{
+			types.NewSystemMessage(summaryPrompt),
+			types.NewUserMessage(transcript.String()),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+	defer stream.Close()
+
+	acc := types.CompletionAccumulator{}
+	for stream.Next() {
+		acc.AddChunk(stream.Current())
+	}
+	if err := stream.Err(); err != nil {
+		return "", fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+	text := strings.TrimSpace(acc.Message("").Content)
+	if text == "" {
+		return "", errors.New("failed to summarize conversation: empty summary")
+	}
+	return text, nil
+}
+
+// writeTranscriptLine renders a message as plain text for the summarizer.
+func writeTranscriptLine(b *strings.Builder, msg types.Message) {
+	switch msg.Role {
+	case types.User:
+		fmt.Fprintf(b, "User: %s\n", msg.Content)
+	case types.Assistant:
+		if msg.Content != "" {
+			fmt.Fprintf(b, "Assistant: %s\n", msg.Content)
+		}
+		for _, call := range msg.ToolCalls {
+			fmt.Fprintf(b, "Assistant called %s(%s)\n", call.Name, call.Args)
+		}
+	case types.Tool:
+		fmt.Fprintf(b, "Tool result: %s\n", msg.Content)
+	case types.System:
+		fmt.Fprintf(b, "System: %s\n", msg.Content)
+	}
+}
+
+// lookup returns the remembered summary covering the longest prefix of
+// messages.
+func (s *Summarizer) lookup(messages []types.Message) (summary, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	best, found := summary{}, false
+	for _, candidate := range s.summaries {
+		if candidate.covered > len(messages) || (found && candidate.covered <= best.covered) {
+			continue
+		}
+		if hashMessages(messages[:candidate.covered]) == candidate.hash {
+			best, found = candidate, true
+		}
+	}
+	return best, found
+}
+
+func (s *Summarizer) remember(entry summary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.summaries = append(s.summaries, entry)
+	if len(s.summaries) > maxSummaries {
+		s.summaries = s.summaries[1:]
+	}
+}
+
+func hashMessages(messages []types.Message) [sha256.Size]byte {
+	h := sha256.New()
+	encoder := json.NewEncoder(h)
+	for _, msg := range messages {
+		encoder.Encode(msg)
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
@@ -0,0 +1,426 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/logkn/agents-go/internal/tools"
+	"github.com/logkn/agents-go/internal/types"
+	"github.com/logkn/agents-go/internal/utils"
+)
+
+const (
+	anthropicBaseURL   = "https://api.anthropic.com"
+	anthropicVersion   = "2023-06-01"
+	anthropicMaxTokens = 4096
+)
+
+// Anthropic serves models through the Anthropic Messages API. Unless the model
+// configures an API key, it is read from the ANTHROPIC_API_KEY environment
+// variable. The model's BaseURL, when set, replaces the default API host.
+type Anthropic struct {
+	// HTTPClient performs the requests; http.DefaultClient is used when nil.
+	HTTPClient *http.Client
+}
+
+// NewAnthropic returns a Provider for the Anthropic Messages API.
+func NewAnthropic() *Anthropic {
+	return &Anthropic{}
+}
+
+type anthropicRequest struct {
+	Model       string               `json:"model"`
+	System      string               `json:"system,omitempty"`
+	Messages    []anthropicMessage   `json:"messages"`
+	Tools       []anthropicTool      `json:"tools,omitempty"`
+	MaxTokens   int                  `json:"max_tokens"`
+	Temperature *float32             `json:"temperature,omitempty"`
+	TopP        *float32             `json:"top_p,omitempty"`
+	Stop        []string             `json:"stop_sequences,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Thinking    *anthropicThinking   `json:"thinking,omitempty"`
+	Stream      bool                 `json:"stream"`
+}
+
+type anthropicToolChoice struct {
+	Type                   string `json:"type"`
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock is a content block of any type; only the fields relevant to
+// Type are populated.
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	Thinking  string          `json:"thinking,omitempty"`
+	Signature string          `json:"signature,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicStreamEvent covers every event type of the streaming API.
+type anthropicStreamEvent struct {
+	Type         string         `json:"type"`
+	Index        int            `json:"index"`
+	ContentBlock anthropicBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		Thinking    string `json:"thinking"`
+		Signature   string `json:"signature"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Usage anthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Stream starts a streaming Messages API request.
+func (p *Anthropic) Stream(ctx context.Context, req types.CompletionRequest) (types.CompletionStream, error) {
+	body, err := json.Marshal(anthropicRequestFrom(req))
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: failed to encode request: %w", err)
+	}
+
+	baseURL := anthropicBaseURL
+	if req.Model.BaseURL != "" {
+		baseURL = strings.TrimSuffix(req.Model.BaseURL, "/")
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("content-type", "application/json")
+	httpReq.Header.Set("accept", "text/event-stream")
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+	httpReq.Header.Set("x-api-key", req.Model.ResolveAPIKey("ANTHROPIC_API_KEY"))
+	for key, value := range req.Model.ExtraHeaders {
+		httpReq.Header.Set(key, value)
+	}
+
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newHTTPError("anthropic", resp)
+	}
+
+	return &anthropicStream{
+		body:      resp.Body,
+		events:    newSSEReader(resp.Body),
+		toolIndex: map[int]int{},
+	}, nil
+}
+
+// anthropicRequestFrom maps a completion request onto the Messages API.
+func anthropicRequestFrom(req types.CompletionRequest) anthropicRequest {
+	model := req.Model
+	out := anthropicRequest{
+		Model:     model.Model,
+		MaxTokens: anthropicMaxTokens,
+		Stream:    true,
+		Tools:     utils.MapSlice(req.Tools, anthropicToolFrom),
+		Stop:      model.Stop,
+	}
+	if model.MaxTokens > 0 {
+		out.MaxTokens = model.MaxTokens
+	}
+	out.TopP = model.TopP
+
+	if budget := model.ThinkingBudget; budget > 0 {
+		out.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: budget}
+		// max_tokens must leave room for the answer after thinking
+		out.MaxTokens += budget
+	} else {
+		out.Temperature = model.Temperature
+	}
+	if len(out.Tools) > 0 {
+		out.ToolChoice = anthropicToolChoiceFrom(model)
+	}
+
+	systemPrompts := []string{}
+	for _, msg := range req.Messages {
+		if msg.Role == types.System {
+			systemPrompts = append(systemPrompts, msg.Content)
+			continue
+		}
+
+		role, blocks := anthropicBlocksFrom(msg)
+		if len(blocks) == 0 {
+			continue
+		}
+		// the API expects alternating roles, so merge consecutive messages
+		// such as several tool results
+		if n := len(out.Messages); n > 0 && out.Messages[n-1].Role == role {
+			out.Messages[n-1].Content = append(out.Messages[n-1].Content, blocks...)
+			continue
+		}
+		out.Messages = append(out.Messages, anthropicMessage{Role: role, Content: blocks})
+	}
+	out.System = strings.Join(systemPrompts, "\n\n")
+	return out
+}
+
+// anthropicBlocksFrom converts a message into its role and content blocks.
+func anthropicBlocksFrom(msg types.Message) (string, []anthropicBlock) {
+	switch msg.Role {
+	case types.Assistant:
+		blocks := []anthropicBlock{}
+		if msg.Reasoning != "" && msg.ReasoningSignature != "" {
+			blocks = append(blocks, anthropicBlock{
+				Type:      "thinking",
+				Thinking:  msg.Reasoning,
+				Signature: msg.ReasoningSignature,
+			})
+		}
+		if msg.Content != "" {
+			blocks = append(blocks, anthropicBlock{Type: "text", Text: msg.Content})
+		}
+		for _, call := range msg.ToolCalls {
+			input := json.RawMessage(call.Args)
+			if !json.Valid(input) {
+				input = json.RawMessage("{}")
+			}
+			blocks = append(blocks, anthropicBlock{
+				Type:  "tool_use",
+				ID:    call.ID,
+				Name:  call.Name,
+				Input: input,
+			})
+		}
+		return "assistant", blocks
+	case types.Tool:
+		return "user", []anthropicBlock{{
+			Type:      "tool_result",
+			ToolUseID: msg.ID,
+			Content:   msg.Content,
+		}}
+	default:
+		if msg.Content == "" {
+			return "user", nil
+		}
+		return "user", []anthropicBlock{{Type: "text", Text: msg.Content}}
+	}
+}
+
+// anthropicToolChoiceFrom maps the model's tool settings, returning nil when
+// the API default applies.
+func anthropicToolChoiceFrom(model types.ModelConfig) *anthropicToolChoice {
+	choice := &anthropicToolChoice{Type: "auto"}
+	switch model.ToolChoice {
+	case "", types.ToolChoiceAuto:
+	case types.ToolChoiceNone:
+		choice.Type = "none"
+	case types.ToolChoiceRequired:
+		choice.Type = "any"
+	default:
+		choice.Type = "tool"
+		choice.Name = model.ToolChoice
+	}
+	if model.ParallelToolCalls != nil && !*model.ParallelToolCalls && choice.Type != "none" {
+		choice.DisableParallelToolUse = true
+	}
+	if *choice == (anthropicToolChoice{Type: "auto"}) {
+		return nil
+	}
+	return choice
+}
+
+func anthropicToolFrom(def tools.Definition) anthropicTool {
+	return anthropicTool{
+		Name:        def.Name,
+		Description: def.Description,
+		InputSchema: utils.InlineRootRef(def.Parameters),
+	}
+}
+
+// anthropicFinishReason maps stop reasons onto the OpenAI vocabulary used by
+// the rest of the runner.
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens":
+		return "length"
+	}
+	return stopReason
+}
+
+// anthropicStream turns Messages API events into completion chunks.
+type anthropicStream struct {
+	body    io.ReadCloser
+	events  *sseReader
+	current types.CompletionChunk
+	err     error
+	done    bool
+	// toolIndex maps content block indices to tool call indices.
+	toolIndex  map[int]int
+	usageInput int
+}
+
+func (s *anthropicStream) Next() bool {
+	for !s.done {
+		event, err := s.events.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				s.err = err
+			}
+			s.done = true
+			return false
+		}
+		if event.Data == "" {
+			continue
+		}
+
+		var payload anthropicStreamEvent
+		if err := json.Unmarshal([]byte(event.Data), &payload); err != nil {
+			s.err = fmt.Errorf("anthropic: failed to decode %s event: %w", event.Event, err)
+			s.done = true
+			return false
+		}
+
+		chunk, ok := s.handle(payload)
+		if s.err != nil {
+			return false
+		}
+		if ok {
+			s.current = chunk
+			return true
+		}
+	}
+	return false
+}
+
+// handle processes one event and reports whether it produced a chunk.
+func (s *anthropicStream) handle(event anthropicStreamEvent) (types.CompletionChunk, bool) {
+	switch event.Type {
+	case "message_start":
+		s.usageInput = event.Message.Usage.InputTokens
+	case "content_block_start":
+		switch event.ContentBlock.Type {
+		case "tool_use":
+			index := len(s.toolIndex)
+			s.toolIndex[event.Index] = index
+			return types.CompletionChunk{ToolCalls: []types.ToolCallDelta{{
+				Index: index,
+				ID:    event.ContentBlock.ID,
+				Name:  event.ContentBlock.Name,
+			}}}, true
+		case "text":
+			if event.ContentBlock.Text != "" {
+				return types.CompletionChunk{Content: event.ContentBlock.Text}, true
+			}
+		case "thinking":
+			if event.ContentBlock.Thinking != "" {
+				return types.CompletionChunk{Reasoning: event.ContentBlock.Thinking}, true
+			}
+		}
+	case "content_block_delta":
+		switch event.Delta.Type {
+		case "text_delta":
+			return types.CompletionChunk{Content: event.Delta.Text}, true
+		case "thinking_delta":
+			return types.CompletionChunk{Reasoning: event.Delta.Thinking}, true
+		case "signature_delta":
+			return types.CompletionChunk{ReasoningSignature: event.Delta.Signature}, true
+		case "input_json_delta":
+			return types.CompletionChunk{ToolCalls: []types.ToolCallDelta{{
+				Index: s.toolIndex[event.Index],
+				Args:  event.Delta.PartialJSON,
+			}}}, true
+		}
+	case "message_delta":
+		chunk := types.CompletionChunk{
+			FinishReason: anthropicFinishReason(event.Delta.StopReason),
+			Usage: &types.Usage{
+				InputTokens:  s.usageInput,
+				OutputTokens: event.Usage.OutputTokens,
+				TotalTokens:  s.usageInput + event.Usage.OutputTokens,
+			},
+		}
+		if event.Delta.StopReason == "refusal" {
+			chunk.Refusal = "the model declined to respond"
+		}
+		return chunk, true
+	case "message_stop":
+		s.done = true
+	case "error":
+		s.err = anthropicStreamError(event.Error.Type, event.Error.Message)
+		s.done = true
+	}
+	return types.CompletionChunk{}, false
+}
+
+// anthropicStreamError converts an in-stream error event, reporting transient
+// error types with their equivalent HTTP status so they can be retried.
+func anthropicStreamError(errType, message string) error {
+	status := 0
+	switch errType {
+	case "overloaded_error":
+		status = 529
+	case "rate_limit_error":
+		status = http.StatusTooManyRequests
+	case "api_error":
+		status = http.StatusInternalServerError
+	default:
+		return fmt.Errorf("anthropic: %s: %s", errType, message)
+	}
+	return &HTTPError{Provider: "anthropic", StatusCode: status, Body: errType + ": " + message}
+}
+
+func (s *anthropicStream) Current() types.CompletionChunk {
+	return s.current
+}
+
+func (s *anthropicStream) Err() error {
+	return s.err
+}
+
+func (s *anthropicStream) Close() error {
+	return s.body.Close()
+}
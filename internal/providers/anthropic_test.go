@@ -0,0 +1,169 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/logkn/agents-go/internal/tools"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// replayServer answers every request with the recorded SSE file and captures
+// the decoded request body.
+func replayServer(t *testing.T, fixture string, captured *anthropicRequest) *httptest.Server {
+	t.Helper()
+	recorded, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("anthropic-version") == "" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if captured != nil {
+			json.Unmarshal(body, captured)
+		}
+		w.Header().Set("content-type", "text/event-stream")
+		w.Write(recorded)
+	}))
+}
+
+func TestAnthropicStreamReplay(t *testing.T) {
+	var captured anthropicRequest
+	server := replayServer(t, "testdata/anthropic_tool_use.sse", &captured)
+	defer server.Close()
+
+	req := types.CompletionRequest{
+		Model: types.ModelConfig{Model: "claude-sonnet-4-5", BaseURL: server.URL, ThinkingBudget: 1024},
+		Messages: []types.Message{
+			types.NewSystemMessage("Be brief."),
+			types.NewUserMessage("Weather in Paris?"),
+		},
+		Tools: []tools.Definition{{Name: "get_weather", Description: "Get the weather.", Parameters: map[string]any{"type": "object"}}},
+	}
+	stream, err := NewAnthropic().Stream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer stream.Close()
+
+	acc := types.CompletionAccumulator{}
+	for stream.Next() {
+		acc.AddChunk(stream.Current())
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+
+	if captured.System != "Be brief." || len(captured.Messages) != 1 || captured.Thinking == nil {
+		t.Fatalf("unexpected request %+v", captured)
+	}
+	if captured.Tools[0].Name != "get_weather" {
+		t.Fatalf("tool not forwarded")
+	}
+
+	msg := acc.Message("claude")
+	if msg.Content != "Let me check that for you." {
+		t.Fatalf("unexpected content %q", msg.Content)
+	}
+	if msg.Reasoning != "The user wants the weather, so I should call the tool." || msg.ReasoningSignature != "EqQBCgIYAhIM" {
+		t.Fatalf("unexpected reasoning %q / %q", msg.Reasoning, msg.ReasoningSignature)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0] != (types.ToolCall{ID: "toolu_01", Name: "get_weather", Args: `{"location": "Paris"}`}) {
+		t.Fatalf("unexpected tool calls %+v", msg.ToolCalls)
+	}
+	if acc.FinishReason != "tool_calls" {
+		t.Fatalf("unexpected finish reason %q", acc.FinishReason)
+	}
+	if acc.Usage == nil || acc.Usage.InputTokens != 42 || acc.Usage.OutputTokens != 87 {
+		t.Fatalf("unexpected usage %+v", acc.Usage)
+	}
+}
+
+func TestAnthropicStreamError(t *testing.T) {
+	server := replayServer(t, "testdata/anthropic_overloaded.sse", nil)
+	defer server.Close()
+
+	req := types.CompletionRequest{
+		Model:    types.ModelConfig{Model: "claude-sonnet-4-5", BaseURL: server.URL},
+		Messages: []types.Message{types.NewUserMessage("hi")},
+	}
+	stream, err := NewAnthropic().Stream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer stream.Close()
+	for stream.Next() {
+	}
+	if stream.Err() == nil {
+		t.Fatalf("expected the error event to surface")
+	}
+}
+
+func TestAnthropicRequestMapping(t *testing.T) {
+	temperature := float32(0.5)
+	req := types.CompletionRequest{
+		Model: types.ModelConfig{Model: "claude", Temperature: &temperature},
+		Messages: []types.Message{
+			types.NewUserMessage("hi"),
+			types.NewAssistantMessage("", "bot", []types.ToolCall{
+				{ID: "a", Name: "one", Args: `{"x":1}`},
+				{ID: "b", Name: "two", Args: `not json`},
+			}),
+			types.NewToolMessage("a", "first"),
+			types.NewToolMessage("b", "second"),
+		},
+	}
+	out := anthropicRequestFrom(req)
+	if len(out.Messages) != 3 {
+		t.Fatalf("expected tool results to be merged, got %d messages", len(out.Messages))
+	}
+	results := out.Messages[2]
+	if results.Role != "user" || len(results.Content) != 2 || results.Content[1].ToolUseID != "b" {
+		t.Fatalf("unexpected tool results %+v", results)
+	}
+	if string(out.Messages[1].Content[1].Input) != "{}" {
+		t.Fatalf("invalid arguments should be replaced by an empty object")
+	}
+	if out.Temperature == nil || *out.Temperature != 0.5 {
+		t.Fatalf("temperature not forwarded")
+	}
+}
+
+func TestAnthropicSamplingSettings(t *testing.T) {
+	parallel := false
+	topP := float32(0.9)
+	req := types.CompletionRequest{
+		Model: types.ModelConfig{
+			Model:             "claude-sonnet-4-5",
+			MaxTokens:         512,
+			TopP:              &topP,
+			Stop:              []string{"END"},
+			ToolChoice:        types.ToolChoiceRequired,
+			ParallelToolCalls: &parallel,
+		},
+		Messages: []types.Message{types.NewUserMessage("hi")},
+		Tools:    []tools.Definition{{Name: "get_weather", Parameters: map[string]any{"type": "object"}}},
+	}
+
+	out := anthropicRequestFrom(req)
+	if out.MaxTokens != 512 || out.TopP == nil || *out.TopP != 0.9 || out.Stop[0] != "END" {
+		t.Fatalf("sampling settings not forwarded: %+v", out)
+	}
+	if out.ToolChoice == nil || *out.ToolChoice != (anthropicToolChoice{Type: "any", DisableParallelToolUse: true}) {
+		t.Fatalf("unexpected tool choice %+v", out.ToolChoice)
+	}
+
+	req.Model.ToolChoice = "get_weather"
+	req.Model.ParallelToolCalls = nil
+	if choice := anthropicRequestFrom(req).ToolChoice; choice == nil || *choice != (anthropicToolChoice{Type: "tool", Name: "get_weather"}) {
+		t.Fatalf("unexpected named tool choice %+v", choice)
+	}
+}
@@ -0,0 +1,78 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// HTTPError is returned when a provider's API answers with a non-success
+// status code.
+type HTTPError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+	// RetryAfter is the delay requested by the server, if any.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s: unexpected status %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// newHTTPError builds an HTTPError from a failed response, consuming its body.
+func newHTTPError(provider string, resp *http.Response) *HTTPError {
+	body, _ := io.ReadAll(resp.Body)
+	err := &HTTPError{Provider: provider, StatusCode: resp.StatusCode, Body: string(body)}
+	if seconds, convErr := strconv.Atoi(resp.Header.Get("retry-after")); convErr == nil {
+		err.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return err
+}
+
+// retryableStatus reports whether a status code signals a transient failure.
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying:
+// rate limiting, server errors and dropped connections.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return retryableStatus(httpErr.StatusCode)
+	}
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return retryableStatus(openaiErr.StatusCode)
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// RetryAfter returns the delay the server asked for before retrying err, or
+// zero when it did not ask for one.
+func RetryAfter(err error) time.Duration {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.RetryAfter
+	}
+	return 0
+}
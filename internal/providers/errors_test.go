@@ -0,0 +1,32 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&HTTPError{StatusCode: 429}, true},
+		{&HTTPError{StatusCode: 503}, true},
+		{fmt.Errorf("wrapped: %w", &HTTPError{StatusCode: 529}), true},
+		{&HTTPError{StatusCode: 400}, false},
+		{&openai.Error{StatusCode: 500}, true},
+		{&openai.Error{StatusCode: 401}, false},
+		{io.ErrUnexpectedEOF, true},
+		{context.Canceled, false},
+		{nil, false},
+	}
+	for _, c := range cases {
+		if got := IsRetryable(c.err); got != c.want {
+			t.Fatalf("IsRetryable(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
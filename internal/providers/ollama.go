@@ -0,0 +1,375 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/logkn/agents-go/internal/tools"
+	"github.com/logkn/agents-go/internal/types"
+	"github.com/logkn/agents-go/internal/utils"
+)
+
+const ollamaBaseURL = "http://localhost:11434"
+
+// Ollama serves models through Ollama's native /api/chat endpoint, which
+// unlike the OpenAI-compatible shim supports thinking control, keep_alive and
+// model options. The model's BaseURL, when set, replaces the default local
+// server address.
+type Ollama struct {
+	// HTTPClient performs the requests; http.DefaultClient is used when nil.
+	HTTPClient *http.Client
+}
+
+// NewOllama returns a Provider for a local Ollama server.
+func NewOllama() *Ollama {
+	return &Ollama{}
+}
+
+type ollamaRequest struct {
+	Model     string          `json:"model"`
+	Messages  []ollamaMessage `json:"messages"`
+	Tools     []ollamaTool    `json:"tools,omitempty"`
+	Stream    bool            `json:"stream"`
+	Think     any             `json:"think,omitempty"`
+	KeepAlive any             `json:"keep_alive,omitempty"`
+	Options   map[string]any  `json:"options,omitempty"`
+	Format    map[string]any  `json:"format,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description,omitempty"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+// ollamaResponse is one line of the streamed NDJSON response.
+type ollamaResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+func (p *Ollama) client() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// baseURL returns the server address, tolerating URLs that point at the
+// OpenAI-compatible /v1 shim.
+func (p *Ollama) baseURL(model types.ModelConfig) string {
+	if model.BaseURL == "" {
+		return ollamaBaseURL
+	}
+	baseURL := strings.TrimSuffix(model.BaseURL, "/")
+	return strings.TrimSuffix(baseURL, "/v1")
+}
+
+// SupportsResponseFormat reports that structured output is requested through
+// the format parameter.
+func (p *Ollama) SupportsResponseFormat() bool {
+	return true
+}
+
+// Stream starts a streaming /api/chat request.
+func (p *Ollama) Stream(ctx context.Context, req types.CompletionRequest) (types.CompletionStream, error) {
+	body, err := json.Marshal(ollamaRequestFrom(req))
+	if err != nil {
+		return nil, fmt.Errorf("ollama: failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL(req.Model)+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("content-type", "application/json")
+	setOllamaHeaders(httpReq, req.Model)
+
+	resp, err := p.client().Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newHTTPError("ollama", resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	return &ollamaStream{body: resp.Body, lines: scanner, callPrefix: newOllamaCallPrefix()}, nil
+}
+
+// ListModels returns the names of the models available on the server.
+func (p *Ollama) ListModels(ctx context.Context, model types.ModelConfig) ([]string, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL(model)+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	setOllamaHeaders(httpReq, model)
+	resp, err := p.client().Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("ollama: failed to list models: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newHTTPError("ollama", resp)
+	}
+
+	var payload struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("ollama: failed to decode model list: %w", err)
+	}
+	names := make([]string, 0, len(payload.Models))
+	for _, m := range payload.Models {
+		names = append(names, m.Name)
+	}
+	return names, nil
+}
+
+// ValidateModel checks that the model has been pulled, naming the available
+// models when it has not.
+func (p *Ollama) ValidateModel(ctx context.Context, model types.ModelConfig) error {
+	available, err := p.ListModels(ctx, model)
+	if err != nil {
+		return err
+	}
+	for _, name := range available {
+		// an untagged model name refers to the :latest tag
+		if name == model.Model || name == model.Model+":latest" {
+			return nil
+		}
+	}
+	if len(available) == 0 {
+		return fmt.Errorf("ollama: model %q not found and no models are available locally; pull it with `ollama pull %s`", model.Model, model.Model)
+	}
+	return fmt.Errorf("ollama: model %q not found; available models: %s", model.Model, strings.Join(available, ", "))
+}
+
+// setOllamaHeaders adds the model's credentials and extra headers, for servers
+// behind an authenticating proxy or hosted Ollama.
+func setOllamaHeaders(httpReq *http.Request, model types.ModelConfig) {
+	if apiKey := model.ResolveAPIKey("OLLAMA_API_KEY"); apiKey != "" {
+		httpReq.Header.Set("authorization", "Bearer "+apiKey)
+	}
+	for key, value := range model.ExtraHeaders {
+		httpReq.Header.Set(key, value)
+	}
+}
+
+// ollamaRequestFrom maps a completion request onto /api/chat.
+func ollamaRequestFrom(req types.CompletionRequest) ollamaRequest {
+	out := ollamaRequest{
+		Model:    req.Model.Model,
+		Stream:   true,
+		Messages: make([]ollamaMessage, 0, len(req.Messages)),
+	}
+	// tool choice is not supported beyond turning tools off
+	if req.Model.ToolChoice != types.ToolChoiceNone {
+		out.Tools = utils.MapSlice(req.Tools, ollamaToolFrom)
+	}
+
+	// models such as gpt-oss take a reasoning effort in place of a switch
+	switch think := req.Model.Think; {
+	case think != nil && !*think:
+		out.Think = false
+	case req.Model.ReasoningEffort != "":
+		out.Think = req.Model.ReasoningEffort
+	case think != nil:
+		out.Think = true
+	}
+	if req.ResponseFormat != nil {
+		out.Format = req.ResponseFormat.Schema
+	}
+
+	switch keepAlive := req.Model.KeepAlive; {
+	case keepAlive < 0:
+		out.KeepAlive = -1
+	case keepAlive > 0:
+		out.KeepAlive = keepAlive.String()
+	}
+
+	options := map[string]any{}
+	if req.Model.Temperature != nil {
+		options["temperature"] = *req.Model.Temperature
+	}
+	if req.Model.TopP != nil {
+		options["top_p"] = *req.Model.TopP
+	}
+	if req.Model.MaxTokens > 0 {
+		options["num_predict"] = req.Model.MaxTokens
+	}
+	if req.Model.Seed != nil {
+		options["seed"] = *req.Model.Seed
+	}
+	if len(req.Model.Stop) > 0 {
+		options["stop"] = req.Model.Stop
+	}
+	if req.Model.NumCtx > 0 {
+		options["num_ctx"] = req.Model.NumCtx
+	}
+	for key, value := range req.Model.Options {
+		options[key] = value
+	}
+	if len(options) > 0 {
+		out.Options = options
+	}
+
+	// tool results reference their call by ID, but Ollama wants the tool name
+	toolNames := map[string]string{}
+	for _, msg := range req.Messages {
+		for _, call := range msg.ToolCalls {
+			toolNames[call.ID] = call.Name
+		}
+	}
+
+	for _, msg := range req.Messages {
+		converted := ollamaMessage{Content: msg.Content}
+		switch msg.Role {
+		case types.System:
+			converted.Role = "system"
+		case types.Assistant:
+			converted.Role = "assistant"
+			if req.Model.SendReasoning {
+				converted.Thinking = msg.Reasoning
+			}
+			for _, call := range msg.ToolCalls {
+				toolCall := ollamaToolCall{}
+				toolCall.Function.Name = call.Name
+				toolCall.Function.Arguments = json.RawMessage(call.Args)
+				if !json.Valid(toolCall.Function.Arguments) {
+					toolCall.Function.Arguments = json.RawMessage("{}")
+				}
+				converted.ToolCalls = append(converted.ToolCalls, toolCall)
+			}
+		case types.Tool:
+			converted.Role = "tool"
+			converted.ToolName = toolNames[msg.ID]
+		default:
+			converted.Role = "user"
+		}
+		out.Messages = append(out.Messages, converted)
+	}
+	return out
+}
+
+func ollamaToolFrom(def tools.Definition) ollamaTool {
+	tool := ollamaTool{Type: "function"}
+	tool.Function.Name = def.Name
+	tool.Function.Description = def.Description
+	tool.Function.Parameters = utils.InlineRootRef(def.Parameters)
+	return tool
+}
+
+// ollamaStream turns NDJSON response lines into completion chunks.
+type ollamaStream struct {
+	body    io.ReadCloser
+	lines   *bufio.Scanner
+	current types.CompletionChunk
+	err     error
+	// callPrefix makes the IDs given to tool calls unique across turns.
+	callPrefix string
+	toolCalls  int
+}
+
+// newOllamaCallPrefix returns a random prefix for the tool call IDs of a
+// response.
+func newOllamaCallPrefix() string {
+	id := make([]byte, 6)
+	rand.Read(id)
+	return "call_" + hex.EncodeToString(id) + "_"
+}
+
+func (s *ollamaStream) Next() bool {
+	for s.lines.Scan() {
+		line := bytes.TrimSpace(s.lines.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var payload ollamaResponse
+		if err := json.Unmarshal(line, &payload); err != nil {
+			s.err = fmt.Errorf("ollama: failed to decode response: %w", err)
+			return false
+		}
+		if payload.Error != "" {
+			s.err = fmt.Errorf("ollama: %s", payload.Error)
+			return false
+		}
+
+		chunk := types.CompletionChunk{
+			Content:   payload.Message.Content,
+			Reasoning: payload.Message.Thinking,
+		}
+		// Ollama sends each tool call whole and without an ID
+		for _, call := range payload.Message.ToolCalls {
+			chunk.ToolCalls = append(chunk.ToolCalls, types.ToolCallDelta{
+				Index: s.toolCalls,
+				ID:    fmt.Sprintf("%s%d", s.callPrefix, s.toolCalls),
+				Name:  call.Function.Name,
+				Args:  string(call.Function.Arguments),
+			})
+			s.toolCalls++
+		}
+		if payload.Done {
+			chunk.FinishReason = payload.DoneReason
+			if s.toolCalls > 0 {
+				chunk.FinishReason = "tool_calls"
+			}
+			chunk.Usage = &types.Usage{
+				InputTokens:  payload.PromptEvalCount,
+				OutputTokens: payload.EvalCount,
+				TotalTokens:  payload.PromptEvalCount + payload.EvalCount,
+			}
+		}
+		s.current = chunk
+		return true
+	}
+	s.err = s.lines.Err()
+	return false
+}
+
+func (s *ollamaStream) Current() types.CompletionChunk {
+	return s.current
+}
+
+func (s *ollamaStream) Err() error {
+	return s.err
+}
+
+func (s *ollamaStream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,157 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logkn/agents-go/internal/types"
+)
+
+func ollamaServer(t *testing.T, captured *ollamaRequest) *httptest.Server {
+	t.Helper()
+	recorded, err := os.ReadFile("testdata/ollama_chat.ndjson")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			w.Write([]byte(`{"models":[{"name":"qwen3:30b-a3b"},{"name":"llama3.2:latest"}]}`))
+		case "/api/chat":
+			if captured != nil {
+				json.NewDecoder(r.Body).Decode(captured)
+			}
+			w.Header().Set("content-type", "application/x-ndjson")
+			w.Write(recorded)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestOllamaStream(t *testing.T) {
+	var captured ollamaRequest
+	server := ollamaServer(t, &captured)
+	defer server.Close()
+
+	think := true
+	topP := float32(0.5)
+	req := types.CompletionRequest{
+		Model: types.ModelConfig{
+			Model:     "qwen3:30b-a3b",
+			BaseURL:   server.URL + "/v1",
+			Think:     &think,
+			KeepAlive: 10 * time.Minute,
+			NumCtx:    8192,
+			TopP:      &topP,
+			Options:   map[string]any{"num_predict": 256},
+		},
+		Messages: []types.Message{
+			types.NewUserMessage("Weather?"),
+			types.NewAssistantMessage("", "bot", []types.ToolCall{{ID: "call_0", Name: "get_weather", Args: `{"city":"Rome"}`}}),
+			types.NewToolMessage("call_0", "sunny"),
+		},
+	}
+	stream, err := NewOllama().Stream(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer stream.Close()
+
+	acc := types.CompletionAccumulator{}
+	for stream.Next() {
+		acc.AddChunk(stream.Current())
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+
+	if captured.Think != true || captured.KeepAlive != "10m0s" {
+		t.Fatalf("think or keep_alive not forwarded: %+v", captured)
+	}
+	if captured.Options["num_ctx"] != float64(8192) || captured.Options["num_predict"] != float64(256) || captured.Options["top_p"] != float64(0.5) {
+		t.Fatalf("options not forwarded: %+v", captured.Options)
+	}
+	if captured.Messages[2].ToolName != "get_weather" {
+		t.Fatalf("tool result should carry the tool name")
+	}
+
+	msg := acc.Message("qwen")
+	if msg.Reasoning != "Need the weather for Paris." {
+		t.Fatalf("unexpected reasoning %q", msg.Reasoning)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Name != "get_weather" || msg.ToolCalls[0].Args != `{"city":"Paris"}` || msg.ToolCalls[0].ID == "" {
+		t.Fatalf("unexpected tool calls %+v", msg.ToolCalls)
+	}
+	if acc.FinishReason != "tool_calls" || acc.Usage.TotalTokens != 55 {
+		t.Fatalf("unexpected finish reason %q or usage %+v", acc.FinishReason, acc.Usage)
+	}
+}
+
+func TestOllamaToolNamesAcrossTurns(t *testing.T) {
+	var captured ollamaRequest
+	replies := []string{"get_weather", "get_time", ""}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&captured)
+		reply := replies[0]
+		replies = replies[1:]
+		w.Header().Set("content-type", "application/x-ndjson")
+		if reply == "" {
+			w.Write([]byte(`{"message":{"role":"assistant","content":"done"},"done":true}` + "\n"))
+			return
+		}
+		w.Write([]byte(`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"` + reply + `","arguments":{}}}]},"done":true}` + "\n"))
+	}))
+	defer server.Close()
+
+	messages := []types.Message{types.NewUserMessage("Weather and time?")}
+	for turn := 0; turn < 3; turn++ {
+		stream, err := NewOllama().Stream(context.Background(), types.CompletionRequest{
+			Model:    types.ModelConfig{Model: "qwen3", BaseURL: server.URL},
+			Messages: messages,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		acc := types.CompletionAccumulator{}
+		for stream.Next() {
+			acc.AddChunk(stream.Current())
+		}
+		stream.Close()
+		msg := acc.Message("qwen")
+		messages = append(messages, msg)
+		for _, call := range msg.ToolCalls {
+			messages = append(messages, types.NewToolMessage(call.ID, "result of "+call.Name))
+		}
+	}
+
+	var names []string
+	for _, msg := range captured.Messages {
+		if msg.Role == "tool" {
+			names = append(names, msg.ToolName)
+		}
+	}
+	if strings.Join(names, ",") != "get_weather,get_time" {
+		t.Fatalf("expected each result to carry its own tool name, got %v", names)
+	}
+}
+
+func TestOllamaValidateModel(t *testing.T) {
+	server := ollamaServer(t, nil)
+	defer server.Close()
+
+	provider := NewOllama()
+	if err := provider.ValidateModel(context.Background(), types.ModelConfig{Model: "llama3.2", BaseURL: server.URL}); err != nil {
+		t.Fatalf("untagged name should match :latest, got %v", err)
+	}
+	err := provider.ValidateModel(context.Background(), types.ModelConfig{Model: "mistral", BaseURL: server.URL})
+	if err == nil || !strings.Contains(err.Error(), "qwen3:30b-a3b") {
+		t.Fatalf("expected error naming the available models, got %v", err)
+	}
+}
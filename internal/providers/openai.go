@@ -0,0 +1,205 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/logkn/agents-go/internal/tools"
+	"github.com/logkn/agents-go/internal/types"
+	"github.com/logkn/agents-go/internal/utils"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/packages/respjson"
+	"github.com/openai/openai-go/packages/ssestream"
+	"github.com/openai/openai-go/shared"
+)
+
+// OpenAI serves models through the OpenAI Chat Completions API or any server
+// compatible with it.
+type OpenAI struct{}
+
+// NewOpenAI returns a Provider for the OpenAI Chat Completions API.
+func NewOpenAI() *OpenAI {
+	return &OpenAI{}
+}
+
+// client builds a client honoring the model's connection settings.
+func (p *OpenAI) client(model types.ModelConfig) openai.Client {
+	// the runner retries failed requests according to the model's policy
+	opts := []option.RequestOption{option.WithMaxRetries(0)}
+	if model.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(model.BaseURL))
+	}
+	// without an explicit key the SDK reads OPENAI_API_KEY itself
+	if apiKey := model.ResolveAPIKey(""); apiKey != "" {
+		opts = append(opts, option.WithAPIKey(apiKey))
+	}
+	for key, value := range model.ExtraHeaders {
+		opts = append(opts, option.WithHeader(key, value))
+	}
+	return openai.NewClient(opts...)
+}
+
+// SupportsResponseFormat reports that structured output is requested through
+// response_format.
+func (p *OpenAI) SupportsResponseFormat() bool {
+	return true
+}
+
+// Stream starts a streaming chat completion.
+func (p *OpenAI) Stream(ctx context.Context, req types.CompletionRequest) (types.CompletionStream, error) {
+	client := p.client(req.Model)
+
+	params := openai.ChatCompletionNewParams{
+		Messages: utils.MapSlice(replayedReasoning(req.Messages, req.Model), types.Message.ToOpenAI),
+		Model:    req.Model.Model,
+		Tools:    utils.MapSlice(req.Tools, tools.Definition.ToOpenAI),
+		// usage is only reported on streams when requested
+		StreamOptions: openai.ChatCompletionStreamOptionsParam{
+			IncludeUsage: openai.Bool(true),
+		},
+	}
+	applyOpenAISampling(&params, req.Model)
+	if format := req.ResponseFormat; format != nil {
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   format.Name,
+					Schema: format.Schema,
+				},
+			},
+		}
+	}
+	return &openAIStream{stream: client.Chat.Completions.NewStreaming(ctx, params)}, nil
+}
+
+// applyOpenAISampling copies the model's sampling settings onto params,
+// leaving unset ones to the server's defaults.
+func applyOpenAISampling(params *openai.ChatCompletionNewParams, model types.ModelConfig) {
+	if model.Temperature != nil {
+		params.Temperature = openai.Float(float64(*model.Temperature))
+	}
+	if model.TopP != nil {
+		params.TopP = openai.Float(float64(*model.TopP))
+	}
+	if model.MaxTokens > 0 {
+		params.MaxCompletionTokens = openai.Int(int64(model.MaxTokens))
+	}
+	if model.Seed != nil {
+		params.Seed = openai.Int(*model.Seed)
+	}
+	if len(model.Stop) > 0 {
+		params.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: model.Stop}
+	}
+	if model.ReasoningEffort != "" {
+		params.ReasoningEffort = shared.ReasoningEffort(model.ReasoningEffort)
+	}
+	// tool settings are rejected by the API when no tools are sent
+	if len(params.Tools) == 0 {
+		return
+	}
+	if model.ParallelToolCalls != nil {
+		params.ParallelToolCalls = openai.Bool(*model.ParallelToolCalls)
+	}
+	switch model.ToolChoice {
+	case "":
+	case types.ToolChoiceAuto, types.ToolChoiceNone, types.ToolChoiceRequired:
+		params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String(model.ToolChoice)}
+	default:
+		params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{
+			OfChatCompletionNamedToolChoice: &openai.ChatCompletionNamedToolChoiceParam{
+				Function: openai.ChatCompletionNamedToolChoiceFunctionParam{Name: model.ToolChoice},
+			},
+		}
+	}
+}
+
+// openAIStream adapts the SDK's SSE stream to types.CompletionStream.
+type openAIStream struct {
+	stream  *ssestream.Stream[openai.ChatCompletionChunk]
+	current types.CompletionChunk
+}
+
+func (s *openAIStream) Next() bool {
+	if !s.stream.Next() {
+		return false
+	}
+	s.current = chunkFromOpenAI(s.stream.Current())
+	return true
+}
+
+func (s *openAIStream) Current() types.CompletionChunk {
+	return s.current
+}
+
+func (s *openAIStream) Err() error {
+	return s.stream.Err()
+}
+
+func (s *openAIStream) Close() error {
+	return s.stream.Close()
+}
+
+// chunkFromOpenAI converts an SDK chunk into our provider-agnostic chunk.
+func chunkFromOpenAI(chunk openai.ChatCompletionChunk) types.CompletionChunk {
+	out := types.CompletionChunk{}
+	if chunk.Usage.TotalTokens > 0 {
+		out.Usage = &types.Usage{
+			InputTokens:  int(chunk.Usage.PromptTokens),
+			OutputTokens: int(chunk.Usage.CompletionTokens),
+			TotalTokens:  int(chunk.Usage.TotalTokens),
+		}
+	}
+	if len(chunk.Choices) == 0 {
+		return out
+	}
+
+	choice := chunk.Choices[0]
+	out.Content = choice.Delta.Content
+	out.Reasoning = openAIReasoning(choice.Delta.JSON.ExtraFields)
+	out.Refusal = choice.Delta.Refusal
+	out.FinishReason = choice.FinishReason
+	for _, call := range choice.Delta.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, types.ToolCallDelta{
+			Index: int(call.Index),
+			ID:    call.ID,
+			Name:  call.Function.Name,
+			Args:  call.Function.Arguments,
+		})
+	}
+	return out
+}
+
+// openAIReasoning returns the reasoning that OpenAI-compatible servers such as
+// vLLM, DeepSeek and OpenRouter stream beside the content.
+func openAIReasoning(fields map[string]respjson.Field) string {
+	for _, name := range []string{"reasoning_content", "reasoning"} {
+		field, ok := fields[name]
+		if !ok {
+			continue
+		}
+		var text string
+		if json.Unmarshal([]byte(field.Raw()), &text) == nil && text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// replayedReasoning returns messages with the reasoning of assistant turns
+// written back inline between <think> tags when the model is configured to
+// see it. Otherwise reasoning is left out of the request.
+func replayedReasoning(messages []types.Message, model types.ModelConfig) []types.Message {
+	if !model.SendReasoning {
+		return messages
+	}
+	out := make([]types.Message, len(messages))
+	for i, msg := range messages {
+		if msg.Role == types.Assistant && msg.Reasoning != "" {
+			msg.Content = "<think>\n" + strings.TrimSpace(msg.Reasoning) + "\n</think>\n\n" + msg.Content
+		}
+		out[i] = msg
+	}
+	return out
+}
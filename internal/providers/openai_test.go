@@ -0,0 +1,101 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/logkn/agents-go/internal/tools"
+	"github.com/logkn/agents-go/internal/types"
+	"github.com/logkn/agents-go/internal/utils"
+	"github.com/openai/openai-go"
+)
+
+func TestOpenAISamplingSettings(t *testing.T) {
+	seed := int64(7)
+	temperature := float32(0.2)
+	model := types.ModelConfig{
+		Model:           "gpt-4.1",
+		Temperature:     &temperature,
+		MaxTokens:       100,
+		Seed:            &seed,
+		Stop:            []string{"END"},
+		ToolChoice:      "get_weather",
+		ReasoningEffort: "low",
+	}
+	params := openai.ChatCompletionNewParams{
+		Model: model.Model,
+		Tools: utils.MapSlice([]tools.Definition{{Name: "get_weather"}}, tools.Definition.ToOpenAI),
+	}
+	applyOpenAISampling(&params, model)
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("failed to encode params: %v", err)
+	}
+	var body map[string]any
+	json.Unmarshal(raw, &body)
+
+	if body["temperature"].(float64) < 0.19 || body["max_completion_tokens"] != float64(100) || body["seed"] != float64(7) {
+		t.Fatalf("sampling settings not forwarded: %s", raw)
+	}
+	if body["reasoning_effort"] != "low" || body["stop"].([]any)[0] != "END" {
+		t.Fatalf("stop or reasoning effort not forwarded: %s", raw)
+	}
+	choice, _ := body["tool_choice"].(map[string]any)
+	if function, _ := choice["function"].(map[string]any); function["name"] != "get_weather" {
+		t.Fatalf("named tool choice not forwarded: %s", raw)
+	}
+	if _, ok := body["top_p"]; ok {
+		t.Fatalf("unset top_p should be omitted: %s", raw)
+	}
+}
+
+func TestExplicitZeroSampling(t *testing.T) {
+	zero := float32(0)
+	model := types.ModelConfig{Model: "deterministic", Temperature: &zero, TopP: &zero}
+
+	params := openai.ChatCompletionNewParams{Model: model.Model}
+	applyOpenAISampling(&params, model)
+	raw, _ := json.Marshal(params)
+	var body map[string]any
+	json.Unmarshal(raw, &body)
+	if body["temperature"] != float64(0) || body["top_p"] != float64(0) {
+		t.Fatalf("openai: explicit zero sampling settings not forwarded: %s", raw)
+	}
+
+	anthropic := anthropicRequestFrom(types.CompletionRequest{Model: model})
+	if anthropic.Temperature == nil || *anthropic.Temperature != 0 || anthropic.TopP == nil || *anthropic.TopP != 0 {
+		t.Fatalf("anthropic: explicit zero sampling settings not forwarded: %+v", anthropic)
+	}
+
+	ollama := ollamaRequestFrom(types.CompletionRequest{Model: model})
+	if ollama.Options["temperature"] != zero || ollama.Options["top_p"] != zero {
+		t.Fatalf("ollama: explicit zero sampling settings not forwarded: %+v", ollama.Options)
+	}
+}
+
+func TestOpenAIReasoningContent(t *testing.T) {
+	var chunk openai.ChatCompletionChunk
+	raw := `{"id":"1","object":"chat.completion.chunk","created":0,"model":"qwen",
+		"choices":[{"index":0,"delta":{"content":"","reasoning_content":"let me think"}}]}`
+	if err := json.Unmarshal([]byte(raw), &chunk); err != nil {
+		t.Fatalf("invalid chunk: %v", err)
+	}
+	if got := chunkFromOpenAI(chunk); got.Reasoning != "let me think" {
+		t.Fatalf("expected reasoning from reasoning_content, got %+v", got)
+	}
+}
+
+func TestOpenAIReplaysReasoningWhenConfigured(t *testing.T) {
+	answer := types.NewAssistantMessage("4", "agent", nil)
+	answer.Reasoning = "2+2"
+	messages := []types.Message{types.NewUserMessage("2+2?"), answer}
+
+	if got := replayedReasoning(messages, types.ModelConfig{}); got[1].Content != "4" {
+		t.Fatalf("expected reasoning to be left out, got %q", got[1].Content)
+	}
+	got := replayedReasoning(messages, types.ModelConfig{SendReasoning: true})
+	if got[1].Content != "<think>\n2+2\n</think>\n\n4" || messages[1].Content != "4" {
+		t.Fatalf("expected reasoning inline on a copy, got %q", got[1].Content)
+	}
+}
@@ -0,0 +1,13 @@
+// Package providers contains the LLM backends that can serve an agent's model.
+package providers
+
+import "github.com/logkn/agents-go/internal/types"
+
+// For returns the provider configured for the model, defaulting to the OpenAI
+// Chat Completions API.
+func For(model types.ModelConfig) types.Provider {
+	if model.Provider != nil {
+		return model.Provider
+	}
+	return NewOpenAI()
+}
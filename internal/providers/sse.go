@@ -0,0 +1,57 @@
+package providers
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// sseEvent is a single server-sent event.
+type sseEvent struct {
+	Event string
+	Data  string
+}
+
+// sseReader decodes a text/event-stream body one event at a time.
+type sseReader struct {
+	scanner *bufio.Scanner
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	scanner := bufio.NewScanner(r)
+	// tool arguments can make single events large
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	return &sseReader{scanner: scanner}
+}
+
+// Next returns the next event. It returns io.EOF once the body is exhausted.
+func (r *sseReader) Next() (sseEvent, error) {
+	var event sseEvent
+	var data []string
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		switch {
+		case line == "":
+			// a blank line dispatches the event, if one was started
+			if event.Event == "" && len(data) == 0 {
+				continue
+			}
+			event.Data = strings.Join(data, "\n")
+			return event, nil
+		case strings.HasPrefix(line, ":"):
+			// comment
+		case strings.HasPrefix(line, "event:"):
+			event.Event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return sseEvent{}, err
+	}
+	if event.Event != "" || len(data) > 0 {
+		event.Data = strings.Join(data, "\n")
+		return event, nil
+	}
+	return sseEvent{}, io.EOF
+}
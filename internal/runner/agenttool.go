@@ -0,0 +1,146 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/logkn/agents-go/internal/tools"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// OutputExtractor turns a finished run of an agent exposed as a tool into the
+// tool's result.
+type OutputExtractor func(resp *AgentResponse) (any, error)
+
+// ExtractFinalAnswer returns the text of the agent's last message.
+func ExtractFinalAnswer(resp *AgentResponse) (any, error) {
+	return resp.Response().Content, nil
+}
+
+// ExtractStructuredOutput returns the structured output of an agent with an
+// OutputType.
+func ExtractStructuredOutput(resp *AgentResponse) (any, error) {
+	return FinalOutput[any](resp)
+}
+
+// ExtractLastMessages returns the last n messages of the agent's run, tool
+// calls and their results included.
+func ExtractLastMessages(n int) OutputExtractor {
+	return func(resp *AgentResponse) (any, error) {
+		messages := resp.FinalConversation()
+		return messages[len(messages)-min(max(n, 0), len(messages)):], nil
+	}
+}
+
+// AgentToolConfig holds the settings of an agent exposed as a tool.
+type AgentToolConfig struct {
+	// MaxTurns caps the LLM calls of each run of the agent.
+	MaxTurns int
+	// Output turns a finished run of the agent into the tool's result.
+	// Defaults to ExtractFinalAnswer.
+	Output OutputExtractor
+}
+
+type AgentToolOption interface {
+	Apply(config *AgentToolConfig)
+}
+
+func (config *AgentToolConfig) Apply(opts ...AgentToolOption) {
+	for _, opt := range opts {
+		opt.Apply(config)
+	}
+}
+
+type agentToolOptionFunc func(*AgentToolConfig)
+
+func (f agentToolOptionFunc) Apply(config *AgentToolConfig) {
+	f(config)
+}
+
+func WithAgentToolMaxTurns(maxTurns int) AgentToolOption {
+	return agentToolOptionFunc(func(config *AgentToolConfig) {
+		config.MaxTurns = maxTurns
+	})
+}
+
+func WithAgentToolOutput(extract OutputExtractor) AgentToolOption {
+	return agentToolOptionFunc(func(config *AgentToolConfig) {
+		config.Output = extract
+	})
+}
+
+// agentTool is an agent exposed as a tool.
+type agentTool[Context any] struct {
+	agent  types.Agent[Context]
+	config AgentToolConfig
+}
+
+// agentToolArgs are the arguments of an agent exposed as a tool.
+type agentToolArgs[Context any] struct {
+	Prompt string `json:"prompt" description:"The request for the agent to carry out"`
+
+	tool *agentTool[Context]
+}
+
+// DecodeArgs reads the prompt of a call, keeping the agent the arguments
+// are bound to.
+func (a agentToolArgs[Context]) DecodeArgs(args string) (tools.ToolArgs[Context], error) {
+	decoded := agentToolArgs[Context]{tool: a.tool}
+	if err := json.Unmarshal([]byte(args), &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// Run runs the agent on its own, outside of any run.
+func (a agentToolArgs[Context]) Run(ctx *Context) any {
+	return a.RunWithContext(context.Background(), ctx)
+}
+
+// RunWithContext runs the agent on the prompt as part of the calling run.
+// Failed runs are reported as errors, and runs stopped along with the caller
+// as cancelled tool calls.
+func (a agentToolArgs[Context]) RunWithContext(runCtx context.Context, ctx *Context) any {
+	agent := a.tool.agent
+	var opts []RunOption
+	if maxTurns := a.tool.config.MaxTurns; maxTurns > 0 {
+		opts = append(opts, WithMaxTurns(maxTurns))
+	}
+
+	resp, err := Run(runCtx, agent, Input{OfString: a.Prompt}, ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("running agent %s: %w", agent.Name, err)
+	}
+	switch resp.Status() {
+	case StatusCancelled:
+		return errToolCallCancelled
+	case StatusFailed:
+		return fmt.Errorf("agent %s failed: %w", agent.Name, resp.Err())
+	}
+
+	output, err := a.tool.config.Output(resp)
+	if err != nil {
+		return fmt.Errorf("agent %s: %w", agent.Name, err)
+	}
+	return output
+}
+
+// AgentTool exposes agent as a tool taking a prompt. Each call runs the agent
+// on the prompt with the caller's Context, as part of the calling run:
+// stopping the caller stops it, its events are forwarded to the caller's
+// stream with ParentToolCallID set to the call, its tool calls waiting for
+// approval can be resolved through the caller's AgentResponse, and its usage
+// adds to the caller's, counting towards the caller's MaxTotalTokens.
+func AgentTool[Context any](agent types.Agent[Context], name, description string, opts ...AgentToolOption) tools.Tool[Context] {
+	config := AgentToolConfig{Output: ExtractFinalAnswer}
+	config.Apply(opts...)
+	if config.Output == nil {
+		config.Output = ExtractFinalAnswer
+	}
+	return tools.Tool[Context]{
+		Name:        name,
+		Description: description,
+		Args:        agentToolArgs[Context]{tool: &agentTool[Context]{agent: agent, config: config}},
+	}
+}
@@ -0,0 +1,196 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/logkn/agents-go/internal/tools"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+var (
+	// ErrToolCallRejected is the result recorded for tool calls rejected
+	// during approval. The model sees it, along with the reason, as the
+	// tool's output.
+	ErrToolCallRejected = errors.New("tool call rejected by the user")
+	// ErrNoPendingApproval is returned when resolving a tool call that is
+	// not waiting for approval.
+	ErrNoPendingApproval = errors.New("no pending approval for tool call")
+	// ErrAmbiguousApproval is returned when resolving a tool call by an ID
+	// that calls of several runs waiting for approval share, such as a run
+	// and an agent it runs as a tool. ResolveInRun tells them apart.
+	ErrAmbiguousApproval = errors.New("tool call ID shared by several runs")
+)
+
+// ApprovalDecision resolves a tool call waiting for approval.
+type ApprovalDecision struct {
+	Approved bool
+	// Reason tells the model why the call was rejected.
+	Reason string
+	// Args, if set on an approved call, replaces the arguments the model
+	// chose.
+	Args string
+}
+
+// err returns the tool result recorded for a rejected call.
+func (d ApprovalDecision) err() error {
+	if d.Reason == "" {
+		return ErrToolCallRejected
+	}
+	return fmt.Errorf("%w: %s", ErrToolCallRejected, d.Reason)
+}
+
+// pendingApproval is a tool call held until the caller decides on it.
+type pendingApproval struct {
+	request  ApprovalRequestedEvent
+	decision chan ApprovalDecision
+	// owners are the responses it can be resolved through: the run's own,
+	// then those of the runs it is nested in.
+	owners []*AgentResponse
+}
+
+// requestApproval registers a tool call as waiting for approval, with this
+// response and those of the runs it is nested in, and returns the channel its
+// decision arrives on.
+func (ar *AgentResponse) requestApproval(request ApprovalRequestedEvent) <-chan ApprovalDecision {
+	pending := &pendingApproval{request: request, decision: make(chan ApprovalDecision, 1)}
+	for owner := ar; owner != nil; owner = owner.parent {
+		pending.owners = append(pending.owners, owner)
+	}
+	for _, owner := range pending.owners {
+		owner.mu.Lock()
+		owner.approvals = append(owner.approvals, pending)
+		owner.mu.Unlock()
+	}
+	return pending.decision
+}
+
+// takeApproval unregisters the pending approval of a tool call from every
+// response it can be resolved through. An empty runID matches calls of any
+// run, as long as only one of them has the ID.
+func (ar *AgentResponse) takeApproval(runID, toolCallID string) (*pendingApproval, error) {
+	ar.mu.Lock()
+	var matches []*pendingApproval
+	for _, p := range ar.approvals {
+		if p.request.ToolCallID == toolCallID && (runID == "" || p.request.RunID == runID) {
+			matches = append(matches, p)
+		}
+	}
+	ar.mu.Unlock()
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w %s", ErrNoPendingApproval, toolCallID)
+	case 1:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrAmbiguousApproval, toolCallID)
+	}
+	pending := matches[0]
+	// the run's own response settles concurrent attempts
+	if !pending.owners[0].withdrawApproval(pending) {
+		return nil, fmt.Errorf("%w %s", ErrNoPendingApproval, toolCallID)
+	}
+	for _, owner := range pending.owners[1:] {
+		owner.withdrawApproval(pending)
+	}
+	return pending, nil
+}
+
+// withdrawApproval removes pending from the response's approvals and reports
+// whether it was there.
+func (ar *AgentResponse) withdrawApproval(pending *pendingApproval) bool {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	i := slices.Index(ar.approvals, pending)
+	if i < 0 {
+		return false
+	}
+	ar.approvals = slices.Delete(ar.approvals, i, i+1)
+	return true
+}
+
+// PendingApprovals returns the tool calls currently waiting for approval,
+// those of nested runs included, in the order they were requested.
+func (ar *AgentResponse) PendingApprovals() []ApprovalRequestedEvent {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	requests := make([]ApprovalRequestedEvent, len(ar.approvals))
+	for i, pending := range ar.approvals {
+		requests[i] = pending.request
+	}
+	return requests
+}
+
+// Resolve hands the decision on a tool call waiting for approval to the run.
+// It fails with ErrAmbiguousApproval if calls of several runs waiting for
+// approval have the ID.
+func (ar *AgentResponse) Resolve(toolCallID string, decision ApprovalDecision) error {
+	return ar.ResolveInRun("", toolCallID, decision)
+}
+
+// ResolveInRun is like Resolve for the tool call of the run with runID, as
+// given by the call's ApprovalRequestedEvent.
+func (ar *AgentResponse) ResolveInRun(runID, toolCallID string, decision ApprovalDecision) error {
+	if decision.Approved && decision.Args != "" && !json.Valid([]byte(decision.Args)) {
+		return fmt.Errorf("invalid arguments for tool call %s: not valid JSON", toolCallID)
+	}
+	pending, err := ar.takeApproval(runID, toolCallID)
+	if err != nil {
+		return err
+	}
+	pending.decision <- decision
+	return nil
+}
+
+// Approve lets a tool call waiting for approval run as the model requested.
+func (ar *AgentResponse) Approve(toolCallID string) error {
+	return ar.Resolve(toolCallID, ApprovalDecision{Approved: true})
+}
+
+// ApproveWithArgs lets a tool call waiting for approval run with args, a
+// JSON object, instead of the arguments the model chose.
+func (ar *AgentResponse) ApproveWithArgs(toolCallID, args string) error {
+	return ar.Resolve(toolCallID, ApprovalDecision{Approved: true, Args: args})
+}
+
+// Reject skips a tool call waiting for approval. The model is told the call
+// was rejected, and why if reason is not empty.
+func (ar *AgentResponse) Reject(toolCallID, reason string) error {
+	return ar.Resolve(toolCallID, ApprovalDecision{Reason: reason})
+}
+
+// needsApproval applies the agent's approval policy to a tool call.
+func (e *execution[Context]) needsApproval(agent types.Agent[Context], tool tools.Tool[Context], toolcall types.ToolCall) bool {
+	policy := agent.ApprovalPolicy
+	if policy == nil {
+		policy = types.RequireApprovalForMarked[Context]
+	}
+	return policy(e.ctx, tool, toolcall)
+}
+
+// awaitApproval holds a tool call until the caller decides on it. It fails
+// if the run ends first.
+func (e *execution[Context]) awaitApproval(toolcall types.ToolCall) (ApprovalDecision, error) {
+	request := ApprovalRequestedEvent{
+		RunID:      e.runID,
+		ToolCallID: toolcall.ID,
+		Name:       toolcall.Name,
+		RawArgs:    toolcall.Args,
+	}
+	json.Unmarshal([]byte(toolcall.Args), &request.Args)
+
+	decision := e.response.requestApproval(request)
+	e.logger.Info("waiting for tool call approval",
+		"tool_name", toolcall.Name,
+		"tool_call_id", toolcall.ID)
+	e.emit(approvalRequestedEvent(request))
+
+	select {
+	case d := <-decision:
+		return d, nil
+	case <-e.runCtx.Done():
+		e.response.takeApproval(e.runID, toolcall.ID)
+		return ApprovalDecision{}, e.runCtx.Err()
+	}
+}
@@ -1,26 +1,185 @@
 package runner
 
 import (
+	"errors"
 	"time"
 
 	"github.com/logkn/agents-go/internal/types"
+	"github.com/logkn/agents-go/internal/utils"
 )
 
+// RunStartedEvent opens the event stream of a run.
+type RunStartedEvent struct {
+	Model string
+	// Messages counts the conversation the run starts from, including any
+	// session history.
+	Messages int
+}
+
+// TurnStartedEvent marks the start of a turn: one LLM call and the tool calls
+// it requests.
+type TurnStartedEvent struct {
+	Messages int
+}
+
+// LLMRequestSentEvent reports a request sent to the provider. Retried
+// requests are reported again.
+type LLMRequestSentEvent struct {
+	Model    string
+	Messages int
+	Tools    int
+}
+
+// ToolCallDeltaEvent reports a fragment of a tool call's arguments as the
+// model generates them. ToolCallID and Name are repeated on every fragment;
+// Args accumulates the fragments so far.
+type ToolCallDeltaEvent struct {
+	// Index orders the tool calls of one assistant turn.
+	Index      int
+	ToolCallID string
+	Name       string
+	ArgsDelta  string
+	Args       string
+}
+
+// PartialArgs parses the arguments received so far, closing whatever the
+// model has not finished writing. It returns nil if nothing usable has
+// arrived yet.
+func (e ToolCallDeltaEvent) PartialArgs() map[string]any {
+	value, err := utils.ParsePartialJSON(e.Args)
+	if err != nil {
+		return nil
+	}
+	args, _ := value.(map[string]any)
+	return args
+}
+
+// ToolCallStartedEvent reports a tool call about to run. Args holds the
+// decoded arguments, or nil if RawArgs is not a JSON object.
+type ToolCallStartedEvent struct {
+	ToolCallID string
+	Name       string
+	Args       map[string]any
+	RawArgs    string
+}
+
+// ToolCallFinishedEvent reports the outcome of a tool call. Err is set when
+// the tool could not run or returned an error.
+type ToolCallFinishedEvent struct {
+	ToolCallID string
+	Name       string
+	Result     any
+	Duration   time.Duration
+	Err        error
+}
+
+// ApprovalRequestedEvent reports a tool call waiting for approval. The run
+// holds the call until it is resolved through the AgentResponse.
+type ApprovalRequestedEvent struct {
+	// RunID is the run the call belongs to, which differs from the
+	// response's own for calls of nested runs.
+	RunID      string
+	ToolCallID string
+	Name       string
+	Args       map[string]any
+	RawArgs    string
+}
+
+// AgentSwitchedEvent reports that another agent took over the run.
+type AgentSwitchedEvent struct {
+	FromAgent string
+	ToAgent   string
+}
+
+// RunCompletedEvent closes the event stream of a run with its final status.
+// A stopped run delivers it only if its consumer has room for it.
+type RunCompletedEvent struct {
+	Status   RunStatus
+	Err      error
+	Usage    types.Usage
+	CostUSD  float64
+	Duration time.Duration
+}
+
 type HandoffEvent struct {
 	FromAgent string
 	ToAgent   string
 	Prompt    string
+	// Input is the decoded handoff input, a value of the handoff's
+	// InputType, a HandoffPrompt or, when Returning, a HandoffReturn.
+	Input any
+	// Returning is set when control goes back to the agent that handed off.
+	Returning bool
+}
+
+// RetryEvent reports a failed LLM request that is about to be retried. Tokens
+// streamed by the failed attempt should be discarded.
+type RetryEvent struct {
+	// Attempt is the number of the attempt that failed, counting from 1.
+	Attempt     int
+	MaxAttempts int
+	// Delay is how long the runner waits before the next attempt.
+	Delay time.Duration
+	Err   error
+}
+
+// UsageEvent reports the tokens consumed by one LLM call and their estimated
+// cost.
+type UsageEvent struct {
+	Agent   string
+	Model   string
+	Usage   types.Usage
+	CostUSD float64
 }
 
-// AgentEvent is a generic event emitted during a run. Only one of the fields is
-// typically populated depending on what occurred.
+// CompactionEvent reports that the agent's history strategy shortened the
+// conversation sent to the model. Token counts are estimates.
+type CompactionEvent struct {
+	Agent          string
+	MessagesBefore int
+	MessagesAfter  int
+	TokensBefore   int
+	TokensAfter    int
+}
+
+// AgentEvent is a generic event emitted during a run. Only one of the Of
+// fields is typically populated depending on what occurred; the others
+// describe where in the run it happened.
 type AgentEvent struct {
-	Timestamp    time.Time
-	OfToken      string
-	OfMessage    *types.Message
-	OfToolResult ToolResult
-	OfHandoff    *HandoffEvent
-	OfError      error
+	Timestamp time.Time
+	// RunID identifies the run that emitted the event.
+	RunID string
+	// Turn counts the LLM calls made so far, 0 before the first.
+	Turn int
+	// Agent is the name of the agent active when the event was emitted.
+	Agent string
+	// ParentToolCallID is set on events forwarded from a nested run, such
+	// as an agent run as a tool: the ID of the tool call that started it.
+	// RunID and Agent then describe the nested run.
+	ParentToolCallID string
+	// Step is set on events forwarded from the runs of a group, such as a
+	// workflow: the step of the group they belong to.
+	Step string
+
+	OfToken            string
+	OfReasoningToken   string
+	OfMessage          *types.Message
+	OfToolResult       ToolResult
+	OfHandoff          *HandoffEvent
+	OfError            error
+	OfRetry            *RetryEvent
+	OfUsage            *UsageEvent
+	OfCompaction       *CompactionEvent
+	OfRunStarted       *RunStartedEvent
+	OfTurnStarted      *TurnStartedEvent
+	OfLLMRequestSent   *LLMRequestSentEvent
+	OfToolCallDelta    *ToolCallDeltaEvent
+	OfToolCallStarted  *ToolCallStartedEvent
+	OfToolCallFinished *ToolCallFinishedEvent
+	OfAgentSwitched    *AgentSwitchedEvent
+	OfRunCompleted     *RunCompletedEvent
+
+	OfApprovalRequested *ApprovalRequestedEvent
 }
 
 // Token returns the token contained in the event if present.
@@ -28,6 +187,12 @@ func (e *AgentEvent) Token() (string, bool) {
 	return e.OfToken, e.OfToken != ""
 }
 
+// ReasoningToken returns the fragment of the model's reasoning contained in
+// the event if present.
+func (e *AgentEvent) ReasoningToken() (string, bool) {
+	return e.OfReasoningToken, e.OfReasoningToken != ""
+}
+
 // Message returns the message contained in the event if present.
 func (e *AgentEvent) Message() (*types.Message, bool) {
 	if e.OfMessage != nil {
@@ -60,6 +225,111 @@ func (e *AgentEvent) Error() (error, bool) {
 	return nil, false
 }
 
+// GuardrailTripped returns the tripped guardrail if the event reports one.
+func (e *AgentEvent) GuardrailTripped() (*GuardrailTripped, bool) {
+	var tripped *GuardrailTripped
+	if errors.As(e.OfError, &tripped) {
+		return tripped, true
+	}
+	return nil, false
+}
+
+// Retry returns the retry event if present.
+func (e *AgentEvent) Retry() (*RetryEvent, bool) {
+	if e.OfRetry != nil {
+		return e.OfRetry, true
+	}
+	return nil, false
+}
+
+// Usage returns the usage event if present.
+func (e *AgentEvent) Usage() (*UsageEvent, bool) {
+	if e.OfUsage != nil {
+		return e.OfUsage, true
+	}
+	return nil, false
+}
+
+// Compaction returns the compaction event if present.
+func (e *AgentEvent) Compaction() (*CompactionEvent, bool) {
+	if e.OfCompaction != nil {
+		return e.OfCompaction, true
+	}
+	return nil, false
+}
+
+// RunStarted returns the run started event if present.
+func (e *AgentEvent) RunStarted() (*RunStartedEvent, bool) {
+	if e.OfRunStarted != nil {
+		return e.OfRunStarted, true
+	}
+	return nil, false
+}
+
+// TurnStarted returns the turn started event if present.
+func (e *AgentEvent) TurnStarted() (*TurnStartedEvent, bool) {
+	if e.OfTurnStarted != nil {
+		return e.OfTurnStarted, true
+	}
+	return nil, false
+}
+
+// LLMRequestSent returns the request event if present.
+func (e *AgentEvent) LLMRequestSent() (*LLMRequestSentEvent, bool) {
+	if e.OfLLMRequestSent != nil {
+		return e.OfLLMRequestSent, true
+	}
+	return nil, false
+}
+
+// ToolCallDelta returns the tool call fragment if present.
+func (e *AgentEvent) ToolCallDelta() (*ToolCallDeltaEvent, bool) {
+	if e.OfToolCallDelta != nil {
+		return e.OfToolCallDelta, true
+	}
+	return nil, false
+}
+
+// ToolCallStarted returns the tool call started event if present.
+func (e *AgentEvent) ToolCallStarted() (*ToolCallStartedEvent, bool) {
+	if e.OfToolCallStarted != nil {
+		return e.OfToolCallStarted, true
+	}
+	return nil, false
+}
+
+// ToolCallFinished returns the tool call finished event if present.
+func (e *AgentEvent) ToolCallFinished() (*ToolCallFinishedEvent, bool) {
+	if e.OfToolCallFinished != nil {
+		return e.OfToolCallFinished, true
+	}
+	return nil, false
+}
+
+// ApprovalRequested returns the approval request if present.
+func (e *AgentEvent) ApprovalRequested() (*ApprovalRequestedEvent, bool) {
+	if e.OfApprovalRequested != nil {
+		return e.OfApprovalRequested, true
+	}
+	return nil, false
+}
+
+// AgentSwitched returns the agent switch event if present.
+func (e *AgentEvent) AgentSwitched() (*AgentSwitchedEvent, bool) {
+	if e.OfAgentSwitched != nil {
+		return e.OfAgentSwitched, true
+	}
+	return nil, false
+}
+
+// RunCompleted returns the run completed event if present.
+func (e *AgentEvent) RunCompleted() (*RunCompletedEvent, bool) {
+	if e.OfRunCompleted != nil {
+		return e.OfRunCompleted, true
+	}
+	return nil, false
+}
+
 // tokenEvent creates a new AgentEvent containing a token.
 func tokenEvent(token string) AgentEvent {
 	return AgentEvent{
@@ -68,6 +338,14 @@ func tokenEvent(token string) AgentEvent {
 	}
 }
 
+// reasoningTokenEvent creates a new AgentEvent containing reasoning.
+func reasoningTokenEvent(token string) AgentEvent {
+	return AgentEvent{
+		OfReasoningToken: token,
+		Timestamp:        time.Now(),
+	}
+}
+
 // messageEvent creates a new AgentEvent carrying a message.
 func messageEvent(message types.Message) AgentEvent {
 	return AgentEvent{
@@ -97,3 +375,87 @@ func errorEvent(err error) AgentEvent {
 		Timestamp: time.Now(),
 	}
 }
+
+func retryEvent(retry RetryEvent) AgentEvent {
+	return AgentEvent{
+		OfRetry:   &retry,
+		Timestamp: time.Now(),
+	}
+}
+
+func usageEvent(usage UsageEvent) AgentEvent {
+	return AgentEvent{
+		OfUsage:   &usage,
+		Timestamp: time.Now(),
+	}
+}
+
+func compactionEvent(compaction CompactionEvent) AgentEvent {
+	return AgentEvent{
+		OfCompaction: &compaction,
+		Timestamp:    time.Now(),
+	}
+}
+
+func runStartedEvent(event RunStartedEvent) AgentEvent {
+	return AgentEvent{
+		OfRunStarted: &event,
+		Timestamp:    time.Now(),
+	}
+}
+
+func turnStartedEvent(event TurnStartedEvent) AgentEvent {
+	return AgentEvent{
+		OfTurnStarted: &event,
+		Timestamp:     time.Now(),
+	}
+}
+
+func llmRequestSentEvent(event LLMRequestSentEvent) AgentEvent {
+	return AgentEvent{
+		OfLLMRequestSent: &event,
+		Timestamp:        time.Now(),
+	}
+}
+
+func toolCallStartedEvent(event ToolCallStartedEvent) AgentEvent {
+	return AgentEvent{
+		OfToolCallStarted: &event,
+		Timestamp:         time.Now(),
+	}
+}
+
+func toolCallFinishedEvent(event ToolCallFinishedEvent) AgentEvent {
+	return AgentEvent{
+		OfToolCallFinished: &event,
+		Timestamp:          time.Now(),
+	}
+}
+
+func approvalRequestedEvent(event ApprovalRequestedEvent) AgentEvent {
+	return AgentEvent{
+		OfApprovalRequested: &event,
+		Timestamp:           time.Now(),
+	}
+}
+
+func agentSwitchedEvent(event AgentSwitchedEvent) AgentEvent {
+	return AgentEvent{
+		OfAgentSwitched: &event,
+		Timestamp:       time.Now(),
+	}
+}
+
+func runCompletedEvent(event RunCompletedEvent) AgentEvent {
+	return AgentEvent{
+		OfRunCompleted: &event,
+		Timestamp:      time.Now(),
+	}
+}
+
+func toolCallDeltaEvent(event ToolCallDeltaEvent) AgentEvent {
+	return AgentEvent{
+		OfToolCallDelta: &event,
+		Timestamp:       time.Now(),
+	}
+}
@@ -0,0 +1,250 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/logkn/agents-go/internal/tracing"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// GroupFunc starts and waits for the runs making up a group. It is given the
+// group's conversation, session history included, and returns the group's
+// answer once every run it started has finished.
+type GroupFunc func(g *GroupRun, messages []types.Message) (GroupResult, error)
+
+// GroupResult is the outcome of a group.
+type GroupResult struct {
+	// Answer is the group's final message, appended to its conversation.
+	Answer types.Message
+	// Output is the group's structured output, returned by FinalOutput.
+	Output any
+}
+
+// GroupRun is a group in progress.
+type GroupRun struct {
+	runID    string
+	name     string
+	started  time.Time
+	runCtx   context.Context
+	config   RunConfig
+	logger   *slog.Logger
+	messages []types.Message
+	// history counts the leading messages loaded from the session.
+	history  int
+	response *AgentResponse
+	span     *tracing.ActiveSpan
+	parent   *parentRun
+
+	mu sync.Mutex
+	// runs are the runs nested in the group, in the order they started.
+	runs []*AgentResponse
+}
+
+// Group reports the runs started by fn as a single run named name, through
+// one AgentResponse, so a combination of agents can be consumed like one.
+// fn runs on its own goroutine; runs it starts with a context from
+// GroupRun.Context are nested in the group. Of opts, the session and tracer
+// apply to the group, its timeout to the group as a whole and the other
+// limits to each of its runs through GroupRun.RunOptions. A group's state
+// cannot be resumed.
+func Group(runCtx context.Context, name string, input Input, fn GroupFunc, opts ...RunOption) (*AgentResponse, error) {
+	logger := slog.Default()
+	config := RunConfig{}
+	if err := config.Apply(opts...); err != nil {
+		return nil, err
+	}
+
+	logger.Info("starting group run", "group_name", name)
+	messages, history, err := startingMessages(runCtx, input, config, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	runID := newRunID()
+	runCtx, cancel := runContext(runCtx, config)
+	response := newAgentResponse(runID, runCtx, cancel, slices.Clone(messages))
+	parent := parentRunFromContext(runCtx)
+	parent.nest(response)
+
+	g := &GroupRun{
+		runID:    runID,
+		name:     name,
+		started:  time.Now(),
+		runCtx:   runCtx,
+		config:   config,
+		logger:   logger,
+		messages: messages,
+		history:  history,
+		response: response,
+		parent:   parent,
+	}
+
+	// a group without its own tracer joins the trace of its caller
+	parentSpan := tracing.SpanFromContext(runCtx)
+	if config.Tracer != nil || parentSpan == nil {
+		g.span = config.Tracer.Start(tracing.KindRun, name)
+	} else {
+		g.span = parentSpan.StartChild(tracing.KindRun, name)
+	}
+	g.span.SetInput(lastUserInput(messages))
+	g.span.SetAttribute("run_id", runID)
+	g.span.SetAttribute("group", name)
+	g.runCtx = tracing.ContextWithSpan(runCtx, g.span)
+
+	go g.run(fn)
+	return response, nil
+}
+
+// Context returns the context to start the runs of step with. They are
+// traced within the group and nested in it: their events are forwarded to
+// its stream with Step set, their tool calls waiting for approval can be
+// resolved through its response and their usage adds to its own. Stopping
+// the group stops them.
+func (g *GroupRun) Context(step string) context.Context {
+	return contextWithParentRun(g.runCtx, &parentRun{
+		step:     step,
+		deliver:  g.deliver,
+		response: g.response,
+		started:  g.nested,
+	})
+}
+
+// RunOptions returns the options to start the group's runs with: the
+// group's limits, without the settings that apply to the group as a whole.
+func (g *GroupRun) RunOptions() []RunOption {
+	return []RunOption{runOptionFunc(func(config *RunConfig) error {
+		*config = g.config
+		config.Session = nil
+		config.Tracer = nil
+		config.Timeout = 0
+		return nil
+	})}
+}
+
+// nested records a run started in the group.
+func (g *GroupRun) nested(resp *AgentResponse) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.runs = append(g.runs, resp)
+}
+
+// emit stamps an event with the group's identity and delivers it.
+func (g *GroupRun) emit(event AgentEvent) bool {
+	event.RunID = g.runID
+	event.Agent = g.name
+	return g.deliver(event)
+}
+
+// deliver hands an event to the group's response and, if the group is
+// nested, to the run it is nested in.
+func (g *GroupRun) deliver(event AgentEvent) bool {
+	if !g.response.send(event) {
+		return false
+	}
+	g.parent.forward(event)
+	return true
+}
+
+// run is the body of the group goroutine. It always closes the event stream.
+func (g *GroupRun) run(fn GroupFunc) {
+	g.emit(runStartedEvent(RunStartedEvent{Messages: len(g.messages)}))
+
+	result, err := fn(g, slices.Clone(g.messages))
+	if err == nil {
+		g.messages = append(g.messages, result.Answer)
+		g.emit(messageEvent(result.Answer))
+	}
+	if saveErr := g.saveSession(); saveErr != nil {
+		g.logger.Error("failed to save session", "error", saveErr)
+		if err == nil {
+			err = saveErr
+		}
+	}
+
+	status := StatusCompleted
+	switch {
+	case errors.Is(context.Cause(g.runCtx), ErrDeadlineExceeded):
+		status = StatusFailed
+		err = context.Cause(g.runCtx)
+		g.logger.Error("group run exceeded its deadline", "timeout", g.config.Timeout)
+		g.emit(errorEvent(err))
+	case g.runCtx.Err() != nil:
+		status = StatusCancelled
+		err = g.runCtx.Err()
+		g.logger.Info("group run cancelled")
+	case err != nil:
+		status = StatusFailed
+		g.emit(errorEvent(err))
+	}
+
+	// the group's usage is that of its runs, which have finished by now
+	g.mu.Lock()
+	runs := slices.Clone(g.runs)
+	g.mu.Unlock()
+	var usage types.Usage
+	agentUsage := map[string]types.Usage{}
+	var cost float64
+	var chain []string
+	for _, resp := range runs {
+		usage = usage.Add(resp.Usage())
+		for agent, used := range resp.UsageByAgent() {
+			agentUsage[agent] = agentUsage[agent].Add(used)
+		}
+		cost += resp.Cost()
+		chain = append(chain, resp.HandoffChain()...)
+	}
+
+	span := g.span
+	span.SetOutput(g.messages[len(g.messages)-1].Content)
+	span.SetUsage(usage)
+	span.SetAttribute("status", status.String())
+	span.SetAttribute("runs", len(runs))
+	span.SetAttribute("cost_usd", cost)
+	span.SetError(err)
+	span.End()
+
+	g.emit(runCompletedEvent(RunCompletedEvent{
+		Status:   status,
+		Err:      err,
+		Usage:    usage,
+		CostUSD:  cost,
+		Duration: time.Since(g.started),
+	}))
+	g.response.finish(runResult{
+		messages: g.messages,
+		state: RunState{
+			RunID:      g.runID,
+			Agent:      g.name,
+			Messages:   slices.Clone(g.messages),
+			Usage:      usage,
+			AgentUsage: maps.Clone(agentUsage),
+			CostUSD:    cost,
+		},
+		chain:      chain,
+		output:     result.Output,
+		usage:      usage,
+		agentUsage: agentUsage,
+		cost:       cost,
+		status:     status,
+		err:        err,
+	})
+}
+
+// saveSession stores the group's input and answer in its session.
+func (g *GroupRun) saveSession() error {
+	session := g.config.Session
+	if session == nil || len(g.messages) <= g.history {
+		return nil
+	}
+	if err := session.Append(context.WithoutCancel(g.runCtx), g.messages[g.history:]...); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
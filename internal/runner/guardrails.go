@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/logkn/agents-go/internal/tracing"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// ErrGuardrailTripped matches every GuardrailTripped error with errors.Is.
+var ErrGuardrailTripped = errors.New("guardrail tripped")
+
+// GuardrailStage tells which side of the agent a guardrail checks.
+type GuardrailStage string
+
+const (
+	GuardrailInput  GuardrailStage = "input"
+	GuardrailOutput GuardrailStage = "output"
+)
+
+// GuardrailTripped is the error that ends a run when one of its guardrails
+// trips. It is delivered as the run's error event and returned by Err.
+type GuardrailTripped struct {
+	Guardrail string
+	Stage     GuardrailStage
+	Reason    string
+}
+
+func (e *GuardrailTripped) Error() string {
+	return fmt.Sprintf("%s guardrail %q tripped: %s", e.Stage, e.Guardrail, e.Reason)
+}
+
+// Is reports ErrGuardrailTripped as matching.
+func (e *GuardrailTripped) Is(target error) bool {
+	return target == ErrGuardrailTripped
+}
+
+// runGuardrails runs the guardrails concurrently against text. It returns the
+// first trip or failure, cancelling the remaining checks.
+func (e *execution[Context]) runGuardrails(ctx context.Context, stage GuardrailStage, guardrails []types.Guardrail[Context], text string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for _, guardrail := range guardrails {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			span := e.span.StartChild(tracing.KindGuardrail, guardrail.Name)
+			defer span.End()
+			span.SetAttribute("stage", string(stage))
+			span.SetInput(text)
+
+			result, err := guardrail.Check(ctx, e.ctx, text)
+			span.SetAttribute("tripped", result.Tripped)
+			if result.Reason != "" {
+				span.SetOutput(result.Reason)
+			}
+			span.SetError(err)
+			switch {
+			case err != nil && ctx.Err() != nil:
+				// cancelled because another guardrail already decided
+				return
+			case err != nil:
+				err = fmt.Errorf("%s guardrail %q failed: %w", stage, guardrail.Name, err)
+			case result.Tripped:
+				err = &GuardrailTripped{Guardrail: guardrail.Name, Stage: stage, Reason: result.Reason}
+			default:
+				return
+			}
+			once.Do(func() {
+				firstErr = err
+				cancel()
+			})
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		e.logger.Warn("guardrail stopped the run", "stage", stage, "error", firstErr)
+	}
+	return firstErr
+}
+
+// lastUserInput returns the content of the latest user message, which input
+// guardrails check.
+func lastUserInput(messages []types.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == types.User {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+// callModelGuarded makes the first LLM call while the input guardrails run.
+// A tripped guardrail aborts the call and takes precedence over its result.
+func (e *execution[Context]) callModelGuarded() (*types.Message, error) {
+	guardrails := e.agent.InputGuardrails
+	if len(guardrails) == 0 {
+		return e.callModel(e.runCtx)
+	}
+
+	callCtx, cancelCall := context.WithCancel(e.runCtx)
+	defer cancelCall()
+
+	verdict := make(chan error, 1)
+	input := lastUserInput(e.messages)
+	go func() {
+		err := e.runGuardrails(e.runCtx, GuardrailInput, guardrails, input)
+		if err != nil {
+			cancelCall()
+		}
+		verdict <- err
+	}()
+
+	msg, err := e.callModel(callCtx)
+	if guardErr := <-verdict; guardErr != nil {
+		return nil, guardErr
+	}
+	return msg, err
+}
+
+// checkOutput runs the active agent's output guardrails on its final answer.
+func (e *execution[Context]) checkOutput() error {
+	guardrails := e.agent.OutputGuardrails
+	if len(guardrails) == 0 || len(e.messages) == 0 {
+		return nil
+	}
+	return e.runGuardrails(e.runCtx, GuardrailOutput, guardrails, e.messages[len(e.messages)-1].Content)
+}
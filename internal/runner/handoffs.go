@@ -0,0 +1,240 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/logkn/agents-go/internal/tracing"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// findHandoffByToolName searches for a handoff that matches the given tool name
+func findHandoffByToolName[Context any](agent types.Agent[Context], toolName string) *types.Handoff[Context] {
+	for _, handoff := range agent.Handoffs {
+		if handoff.CompleteName() == toolName {
+			return &handoff
+		}
+	}
+	return nil
+}
+
+// handoffFrame is an agent waiting for a handoff to return, with what it was
+// shown of the conversation when it handed off.
+type handoffFrame[Context any] struct {
+	agent    types.Agent[Context]
+	view     []types.Message
+	viewFrom int
+}
+
+// returnHandoff returns the handoff giving control back to the agent that
+// handed off to the active one, or nil if there is none.
+func (e *execution[Context]) returnHandoff() *types.Handoff[Context] {
+	if len(e.callers) == 0 {
+		return nil
+	}
+	handoff := types.ReturnHandoff(&e.callers[len(e.callers)-1].agent)
+	return &handoff
+}
+
+// findHandoff returns agent's handoff whose tool is called name, which may
+// be the return to its caller.
+func (e *execution[Context]) findHandoff(agent types.Agent[Context], name string) (handoff *types.Handoff[Context], returning bool) {
+	if handoff := findHandoffByToolName(agent, name); handoff != nil {
+		return handoff, false
+	}
+	if handoff := e.returnHandoff(); handoff != nil && handoff.CompleteName() == name {
+		return handoff, true
+	}
+	return nil, false
+}
+
+// handoffTransfer is a handoff accepted during a turn, carried out once
+// every tool call of the turn has been answered.
+type handoffTransfer[Context any] struct {
+	handoff types.Handoff[Context]
+	prompt  string
+	// returning is set when control goes back to the calling agent.
+	returning bool
+	span      *tracing.ActiveSpan
+}
+
+// acceptHandoff answers a handoff tool call, checking it against the run's
+// handoff limits, decoding its input and running the handoff's OnHandoff
+// callback. The transfer itself happens in completeHandoff; nil is returned
+// if the handoff was refused, along with an error if that ends the run.
+func (e *execution[Context]) acceptHandoff(handoff types.Handoff[Context], returning bool, toolcall types.ToolCall) (*handoffTransfer[Context], error) {
+	logger := e.logger
+	logger.Info("executing handoff",
+		"from_agent", e.agent.Name,
+		"to_agent", handoff.Agent.Name,
+		"returning", returning,
+		"tool_call_id", toolcall.ID)
+
+	span := e.span.StartChild(tracing.KindHandoff, e.agent.Name+" -> "+handoff.Agent.Name)
+	span.SetAttribute("from_agent", e.agent.Name)
+	span.SetAttribute("to_agent", handoff.Agent.Name)
+	span.SetAttribute("returning", returning)
+	span.SetInput(toolcall.Args)
+
+	if !returning {
+		if err := e.checkHandoff(handoff); err != nil {
+			logger.Warn("handoff refused", "to_agent", handoff.Agent.Name, "error", err)
+			e.refuseHandoff(toolcall, span, err)
+			if errors.Is(err, ErrHandoffCycle) && e.config.HandoffCycles == HandoffCycleRefuse {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+
+	input, err := handoff.DecodeInput(toolcall.Args)
+	if err != nil {
+		err = fmt.Errorf("invalid handoff arguments: %w", err)
+		logger.Error("failed to parse handoff arguments", "error", err)
+		e.refuseHandoff(toolcall, span, err)
+		return nil, nil
+	}
+	if handoff.OnHandoff != nil {
+		if err := handoff.OnHandoff(e.ctx, input); err != nil {
+			logger.Error("OnHandoff callback failed", "error", err)
+			e.refuseHandoff(toolcall, span, fmt.Errorf("handoff failed: %w", err))
+			return nil, nil
+		}
+	}
+
+	prompt := toolcall.Args
+	switch input := input.(type) {
+	case types.HandoffPrompt:
+		prompt = input.Prompt
+	case types.HandoffReturn:
+		prompt = input.Summary
+	}
+
+	// Emit handoff event
+	e.emit(handoffEvent(HandoffEvent{
+		FromAgent: e.agent.Name,
+		ToAgent:   handoff.Agent.Name,
+		Prompt:    prompt,
+		Input:     input,
+		Returning: returning,
+	}))
+
+	// Create tool result message for the handoff
+	result := "Transferring to " + handoff.Agent.Name + " agent"
+	if returning {
+		result = "Returning to " + handoff.Agent.Name + " agent"
+	}
+	e.appendMessage(types.NewToolMessage(toolcall.ID, result))
+	return &handoffTransfer[Context]{handoff: handoff, prompt: prompt, returning: returning, span: span}, nil
+}
+
+// checkHandoff enforces the run's handoff depth and cycle policy on a
+// handoff away from the active agent. A handoff is a cycle if its agent is
+// active or waiting for a return, or if the active agent already made the
+// same transfer during the run.
+func (e *execution[Context]) checkHandoff(handoff types.Handoff[Context]) error {
+	if limit := e.config.MaxHandoffDepth; limit > 0 && len(e.callers) >= limit {
+		return fmt.Errorf("%w: limit of %d reached", ErrMaxHandoffDepth, limit)
+	}
+	if e.config.HandoffCycles == HandoffCycleAllow {
+		return nil
+	}
+
+	target := handoff.Agent.Name
+	if target == e.agent.Name {
+		return fmt.Errorf("%w: cannot transfer to %s, it is already handling the conversation", ErrHandoffCycle, target)
+	}
+	for _, caller := range e.callers {
+		if caller.agent.Name != target {
+			continue
+		}
+		err := fmt.Errorf("%w: cannot transfer to %s, it is waiting for the conversation to return to it", ErrHandoffCycle, target)
+		if back := e.returnHandoff(); back.Agent.Name == target {
+			err = fmt.Errorf("%w; use %s instead", err, back.CompleteName())
+		}
+		return err
+	}
+
+	// returns unwind the callers, so a transfer made earlier in the run may
+	// be repeated back and forth without anyone waiting on it
+	for i := 1; i < len(e.chain); i++ {
+		if e.chain[i-1] == e.agent.Name && e.chain[i] == target {
+			return fmt.Errorf("%w: cannot transfer to %s again, %s already handed the conversation to it in this run",
+				ErrHandoffCycle, target, e.agent.Name)
+		}
+	}
+	return nil
+}
+
+// refuseHandoff answers a handoff tool call that will not be followed with
+// err, so the model can correct itself.
+func (e *execution[Context]) refuseHandoff(toolcall types.ToolCall, span *tracing.ActiveSpan, err error) {
+	span.SetError(err)
+	span.End()
+	e.appendMessage(types.NewToolMessage(toolcall.ID, err))
+}
+
+// completeHandoff switches the run to the handoff's agent. A forward handoff
+// shows it the conversation through the handoff's input filter, followed by
+// the prompt, and leaves the active agent waiting for it to return. A return
+// gives the caller back its view of the conversation, now followed by
+// everything that happened since it handed off.
+func (e *execution[Context]) completeHandoff(transfer handoffTransfer[Context]) error {
+	span := transfer.span
+	defer span.End()
+
+	from := e.agent.Name
+	next := *transfer.handoff.Agent
+	if transfer.returning {
+		caller := e.callers[len(e.callers)-1]
+		e.callers = e.callers[:len(e.callers)-1]
+		next = caller.agent
+		e.view, e.viewFrom = caller.view, caller.viewFrom
+	} else {
+		view, err := e.handoffView(transfer.handoff, transfer.prompt)
+		if err != nil {
+			e.logger.Error("handoff input filter failed", "error", err)
+			span.SetError(err)
+			return err
+		}
+		e.callers = append(e.callers, handoffFrame[Context]{agent: e.agent, view: e.view, viewFrom: e.viewFrom})
+		e.view, e.viewFrom = view, len(e.messages)
+	}
+
+	if err := e.setAgent(next); err != nil {
+		e.logger.Error("failed to switch to handoff agent", "error", err)
+		span.SetError(err)
+		return err
+	}
+	e.chain = append(e.chain, e.agent.Name)
+	e.emit(agentSwitchedEvent(AgentSwitchedEvent{FromAgent: from, ToAgent: e.agent.Name}))
+
+	span.SetAttribute("depth", len(e.callers))
+	e.logger.Info("handoff completed", "new_agent", e.agent.Name, "depth", len(e.callers))
+	return nil
+}
+
+// handoffView returns what the receiving agent of handoff is shown of the
+// conversation so far.
+func (e *execution[Context]) handoffView(handoff types.Handoff[Context], prompt string) ([]types.Message, error) {
+	view := e.conversation()
+	if filter := handoff.InputFilter; filter != nil {
+		filtered, err := filter.Compact(e.runCtx, view)
+		if err != nil {
+			return nil, fmt.Errorf("handoff input filter failed: %w", err)
+		}
+		view = filtered
+	}
+	view = slices.Clone(view)
+
+	if prompt == "" {
+		return view, nil
+	}
+	switch handoff.PromptMode {
+	case types.PromptAsSystemNote:
+		return append(view, types.NewSystemMessage(prompt)), nil
+	default:
+		return append(view, types.NewUserMessage(prompt)), nil
+	}
+}
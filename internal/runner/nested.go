@@ -0,0 +1,57 @@
+package runner
+
+import "context"
+
+// parentRun is the run a nested run was started from, by one of its tool
+// calls, such as an agent run as a tool, or as a step of a group.
+type parentRun struct {
+	toolCallID string
+	step       string
+	// deliver hands an event to the parent's stream.
+	deliver  func(event AgentEvent) bool
+	response *AgentResponse
+	// started, if set, is told of every run nested in the parent.
+	started func(resp *AgentResponse)
+}
+
+type parentRunKey struct{}
+
+// contextWithParentRun returns a copy of ctx under which runs are nested in
+// parent.
+func contextWithParentRun(ctx context.Context, parent *parentRun) context.Context {
+	return context.WithValue(ctx, parentRunKey{}, parent)
+}
+
+// parentRunFromContext returns the run that runs started with ctx are nested
+// in, or nil.
+func parentRunFromContext(ctx context.Context) *parentRun {
+	parent, _ := ctx.Value(parentRunKey{}).(*parentRun)
+	return parent
+}
+
+// nest links the response of a run to its parent, so its approvals can be
+// resolved through the parent's response. It does nothing on a nil parent.
+func (p *parentRun) nest(resp *AgentResponse) {
+	if p == nil {
+		return
+	}
+	resp.parent = p.response
+	if p.started != nil {
+		p.started(resp)
+	}
+}
+
+// forward hands an event of a nested run to the parent, tagged with where
+// the run was nested. It does nothing on a nil parent.
+func (p *parentRun) forward(event AgentEvent) {
+	if p == nil {
+		return
+	}
+	if event.ParentToolCallID == "" {
+		event.ParentToolCallID = p.toolCallID
+	}
+	if event.Step == "" {
+		event.Step = p.step
+	}
+	p.deliver(event)
+}
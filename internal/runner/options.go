@@ -0,0 +1,162 @@
+package runner
+
+import (
+	"errors"
+	"time"
+
+	"github.com/logkn/agents-go/internal/tracing"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// Errors reported when a run exceeds one of its limits. They are wrapped
+// with details, so match them with errors.Is.
+var (
+	ErrMaxTurnsExceeded     = errors.New("max turns exceeded")
+	ErrMaxToolCallsExceeded = errors.New("max tool calls exceeded")
+	ErrMaxTokensExceeded    = errors.New("max total tokens exceeded")
+	ErrDeadlineExceeded     = errors.New("run deadline exceeded")
+	ErrMaxHandoffDepth      = errors.New("max handoff depth exceeded")
+)
+
+// ErrHandoffCycle is reported when a handoff would return the conversation to
+// an agent that is still waiting for it, or repeat a transfer already made in
+// the run, and the run's HandoffCyclePolicy is HandoffCycleFail.
+var ErrHandoffCycle = errors.New("handoff cycle")
+
+// HandoffCyclePolicy decides what happens to a handoff to an agent already in
+// the handoff chain, such as a specialist handing back to the triage agent
+// that called it instead of returning, or the triage agent handing off to the
+// specialist again once it has returned.
+type HandoffCyclePolicy int
+
+const (
+	// HandoffCycleRefuse answers the handoff with an error, pointing the
+	// model to the return tool where there is one.
+	HandoffCycleRefuse HandoffCyclePolicy = iota
+	// HandoffCycleFail ends the run with ErrHandoffCycle.
+	HandoffCycleFail
+	// HandoffCycleAllow follows the handoff.
+	HandoffCycleAllow
+)
+
+// ErrRequestTimeout is reported when a single LLM request exceeds the model's
+// RequestTimeout.
+var ErrRequestTimeout = errors.New("LLM request timed out")
+
+// ErrEmptyResponse is reported when the LLM answers with neither content nor
+// tool calls.
+var ErrEmptyResponse = errors.New("LLM returned an empty response")
+
+// ErrInstructions is reported when the active agent's instructions cannot be
+// rendered, such as a template referring to a missing field.
+var ErrInstructions = errors.New("failed to render instructions")
+
+// RunConfig holds settings that apply to a single run. Zero values mean no
+// limit.
+type RunConfig struct {
+	// MaxTurns caps the number of LLM calls.
+	MaxTurns int
+	// MaxToolCalls caps the number of tool calls executed.
+	MaxToolCalls int
+	// MaxTotalTokens caps the tokens consumed across all LLM calls, as
+	// reported by the provider.
+	MaxTotalTokens int
+	// Timeout caps the wall-clock duration of the run.
+	Timeout time.Duration
+	// ToolConcurrency caps how many tool calls of a turn run at once.
+	ToolConcurrency int
+	// Session, when set, supplies the conversation history and stores the
+	// messages the run adds.
+	Session types.Session
+	// MaxHandoffDepth caps how many agents can be waiting for a handoff to
+	// return at once.
+	MaxHandoffDepth int
+	// HandoffCycles decides what happens to a handoff to an agent already in
+	// the handoff chain.
+	HandoffCycles HandoffCyclePolicy
+	// Tracer records the run as a trace. Without one, a run started with a
+	// context carrying a span, such as a tool's, is traced as part of it.
+	Tracer *tracing.Tracer
+}
+
+type RunOption interface {
+	Apply(config *RunConfig) error
+}
+
+func (config *RunConfig) Apply(opts ...RunOption) error {
+	for _, opt := range opts {
+		if err := opt.Apply(config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type runOptionFunc func(*RunConfig) error
+
+func (f runOptionFunc) Apply(config *RunConfig) error {
+	return f(config)
+}
+
+func WithMaxTurns(maxTurns int) RunOption {
+	return runOptionFunc(func(config *RunConfig) error {
+		config.MaxTurns = maxTurns
+		return nil
+	})
+}
+
+func WithMaxToolCalls(maxToolCalls int) RunOption {
+	return runOptionFunc(func(config *RunConfig) error {
+		config.MaxToolCalls = maxToolCalls
+		return nil
+	})
+}
+
+func WithMaxTotalTokens(maxTotalTokens int) RunOption {
+	return runOptionFunc(func(config *RunConfig) error {
+		config.MaxTotalTokens = maxTotalTokens
+		return nil
+	})
+}
+
+func WithTimeout(timeout time.Duration) RunOption {
+	return runOptionFunc(func(config *RunConfig) error {
+		config.Timeout = timeout
+		return nil
+	})
+}
+
+func WithToolConcurrency(concurrency int) RunOption {
+	return runOptionFunc(func(config *RunConfig) error {
+		config.ToolConcurrency = concurrency
+		return nil
+	})
+}
+
+func WithSession(session types.Session) RunOption {
+	return runOptionFunc(func(config *RunConfig) error {
+		config.Session = session
+		return nil
+	})
+}
+
+func WithTracer(tracer *tracing.Tracer) RunOption {
+	return runOptionFunc(func(config *RunConfig) error {
+		config.Tracer = tracer
+		return nil
+	})
+}
+
+func WithMaxHandoffDepth(depth int) RunOption {
+	return runOptionFunc(func(config *RunConfig) error {
+		config.MaxHandoffDepth = depth
+		return nil
+	})
+}
+
+func WithHandoffCyclePolicy(policy HandoffCyclePolicy) RunOption {
+	return runOptionFunc(func(config *RunConfig) error {
+		config.HandoffCycles = policy
+		return nil
+	})
+}
@@ -0,0 +1,237 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/logkn/agents-go/internal/tools"
+	"github.com/logkn/agents-go/internal/types"
+	"github.com/logkn/agents-go/internal/utils"
+)
+
+// ErrInvalidOutput is reported when the model keeps answering with output
+// that does not decode into the agent's OutputType.
+var ErrInvalidOutput = errors.New("invalid structured output")
+
+const (
+	// finalOutputToolName is the synthetic tool through which providers
+	// without native response formats submit structured output.
+	finalOutputToolName = "final_output"
+	// maxOutputAttempts caps how many answers are checked against the output
+	// type before the run fails.
+	maxOutputAttempts = 3
+)
+
+// outputValidator is implemented by output types that check their own
+// invariants after decoding.
+type outputValidator interface {
+	Validate() error
+}
+
+// outputSpec describes the structured answer expected from an agent.
+type outputSpec struct {
+	typ    reflect.Type
+	format types.ResponseFormat
+	// viaTool is set when the provider cannot enforce the schema and the
+	// answer is requested through the final_output tool instead.
+	viaTool bool
+}
+
+// newOutputSpec builds the output spec for an agent's OutputType, returning
+// nil when the agent answers with free text.
+func newOutputSpec(outputType any, provider types.Provider) (*outputSpec, error) {
+	if outputType == nil {
+		return nil, nil
+	}
+	schema, err := utils.CreateSchema(outputType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output schema: %w", err)
+	}
+
+	typ := reflect.TypeOf(outputType)
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	formatter, ok := provider.(types.ResponseFormatter)
+	return &outputSpec{
+		typ: typ,
+		format: types.ResponseFormat{
+			Name:   outputFormatName(typ),
+			Schema: utils.InlineRootRef(schema),
+		},
+		viaTool: !ok || !formatter.SupportsResponseFormat(),
+	}, nil
+}
+
+// outputFormatName derives a schema name from the type, keeping to the
+// characters APIs accept.
+func outputFormatName(typ reflect.Type) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, typ.Name())
+	if name == "" {
+		return finalOutputToolName
+	}
+	return name
+}
+
+// toolDefinition describes the synthetic final_output tool.
+func (s *outputSpec) toolDefinition() tools.Definition {
+	return tools.Definition{
+		Name:        finalOutputToolName,
+		Description: "Submit your final answer. Call this once you are done instead of replying with text.",
+		Parameters:  s.format.Schema,
+	}
+}
+
+// decode parses raw into a new value of the output type. Unknown fields,
+// missing required fields and errors from a Validate method are reported so
+// the model can correct its answer.
+func (s *outputSpec) decode(raw string) (any, error) {
+	raw = stripCodeFence(raw)
+
+	value := reflect.New(s.typ)
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value.Interface()); err != nil {
+		return nil, err
+	}
+
+	if required, ok := s.format.Schema["required"].([]any); ok {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+			return nil, err
+		}
+		for _, name := range required {
+			if _, ok := fields[name.(string)]; !ok {
+				return nil, fmt.Errorf("missing required field %q", name)
+			}
+		}
+	}
+
+	if validator, ok := value.Interface().(outputValidator); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return value.Elem().Interface(), nil
+}
+
+// stripCodeFence removes a markdown code fence models sometimes wrap JSON in.
+func stripCodeFence(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if !strings.HasPrefix(raw, "```") {
+		return raw
+	}
+	raw = strings.TrimPrefix(raw, "```")
+	raw = strings.TrimPrefix(raw, "json")
+	raw = strings.TrimSuffix(raw, "```")
+	return strings.TrimSpace(raw)
+}
+
+// isAnswer reports whether an assistant turn is an attempt at the final
+// answer rather than a step that uses tools.
+func (s *outputSpec) isAnswer(msg types.Message) bool {
+	if len(msg.ToolCalls) == 0 {
+		return true
+	}
+	_, found := findToolCall(msg.ToolCalls, finalOutputToolName)
+	return s.viaTool && found
+}
+
+// handleOutput checks an answer against the agent's output type. It reports
+// whether the run is finished; when the answer is invalid, the error is sent
+// back to the model so it can try again.
+func (e *execution[Context]) handleOutput(msg types.Message) (bool, error) {
+	spec := e.output
+	raw, call, found := msg.Content, types.ToolCall{}, true
+	if spec.viaTool {
+		call, found = findToolCall(msg.ToolCalls, finalOutputToolName)
+		raw = call.Args
+	}
+
+	var err error
+	if found {
+		var output any
+		if output, err = spec.decode(raw); err == nil {
+			if spec.viaTool {
+				// record the submission as a plain answer so the transcript
+				// carries no unanswered tool calls
+				msg.Content = call.Args
+				msg.ToolCalls = nil
+			}
+			e.appendMessage(msg)
+			e.finalOutput = output
+			return true, nil
+		}
+	} else {
+		err = fmt.Errorf("no %s tool call", finalOutputToolName)
+	}
+
+	e.outputAttempts++
+	e.logger.Warn("invalid structured output", "attempt", e.outputAttempts, "error", err)
+	if e.outputAttempts >= maxOutputAttempts {
+		e.appendMessage(msg)
+		e.skipToolCalls(msg.ToolCalls, ErrInvalidOutput)
+		return true, fmt.Errorf("%w: %w", ErrInvalidOutput, err)
+	}
+
+	e.appendMessage(msg)
+	if !spec.viaTool {
+		e.appendMessage(types.NewUserMessage(fmt.Sprintf(
+			"Your answer is not valid JSON for the required schema: %v. Reply again with only the corrected JSON.", err)))
+		return false, nil
+	}
+	if !found {
+		e.appendMessage(types.NewUserMessage(fmt.Sprintf(
+			"Submit your final answer by calling the %s tool.", finalOutputToolName)))
+		return false, nil
+	}
+
+	// answer the rejected submission, then run whatever else was called
+	e.appendMessage(types.NewToolMessage(call.ID, fmt.Sprintf(
+		"Invalid output: %v. Call %s again with corrected arguments.", err, finalOutputToolName)))
+	others := slices.DeleteFunc(slices.Clone(msg.ToolCalls), func(tc types.ToolCall) bool {
+		return tc.ID == call.ID
+	})
+	return false, e.runToolCalls(others)
+}
+
+// findToolCall returns the first call to the named tool.
+func findToolCall(toolcalls []types.ToolCall, name string) (types.ToolCall, bool) {
+	for _, toolcall := range toolcalls {
+		if toolcall.Name == name {
+			return toolcall, true
+		}
+	}
+	return types.ToolCall{}, false
+}
+
+// FinalOutput waits for the run to finish and returns its structured output,
+// decoded into the agent's OutputType.
+func FinalOutput[T any](ar *AgentResponse) (T, error) {
+	var zero T
+	if err := ar.Err(); err != nil {
+		return zero, err
+	}
+
+	ar.mu.Lock()
+	output := ar.result.output
+	ar.mu.Unlock()
+	if output == nil {
+		return zero, errors.New("run produced no structured output; set the agent's OutputType")
+	}
+	typed, ok := output.(T)
+	if !ok {
+		return zero, fmt.Errorf("final output is %T, not %T", output, zero)
+	}
+	return typed, nil
+}
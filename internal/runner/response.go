@@ -1,79 +1,261 @@
 package runner
 
 import (
+	"context"
+	"maps"
+	"slices"
 	"sync"
 
 	"github.com/logkn/agents-go/internal/types"
 )
 
+// RunStatus describes how a run ended.
+type RunStatus int
+
+// Enumeration of run statuses.
+const (
+	StatusRunning   RunStatus = iota // The run has not finished yet
+	StatusCompleted                  // The assistant produced a final answer
+	StatusCancelled                  // The run was stopped or its context cancelled
+	StatusFailed                     // The run ended with an error
+)
+
+// String returns a human-readable name for the status.
+func (s RunStatus) String() string {
+	switch s {
+	case StatusRunning:
+		return "running"
+	case StatusCompleted:
+		return "completed"
+	case StatusCancelled:
+		return "cancelled"
+	case StatusFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
 // AgentResponse collects all events produced during a run and exposes helper
 // methods to access them.
 type AgentResponse struct {
-	// events is the internal event bus used during streaming.
+	runID string
+	// events is the internal event bus used during streaming. The run closes
+	// it once it has finished.
 	events chan AgentEvent
+	// stream is the consumer-facing copy of events, created on first use.
+	stream chan AgentEvent
+	// done is closed once the run has finished and its result is recorded.
+	done chan struct{}
+	// ctx is cancelled by Stop or by the caller's context.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu sync.Mutex
 	// pastEvents stores everything that has already been observed.
-	pastEvents   []AgentEvent
-	pastMessages []types.Message
-	// closed tracks if the channel has been closed to prevent double-close
-	closed bool
-	mu     sync.Mutex
+	pastEvents []AgentEvent
+	result     runResult
+	// approvals are the tool calls waiting for the caller's decision,
+	// including those of nested runs.
+	approvals []*pendingApproval
+	// parent is the response of the run this one is nested in, if any.
+	parent *AgentResponse
+}
+
+// runResult is the outcome of a run, recorded once it has finished.
+type runResult struct {
+	messages []types.Message
+	// output is the decoded structured answer, if the agent has an OutputType.
+	output     any
+	usage      types.Usage
+	agentUsage map[string]types.Usage
+	cost       float64
+	status     RunStatus
+	err        error
+	// state is what Resume needs to continue the run.
+	state RunState
+	// chain lists the agents that were active, in order.
+	chain []string
 }
 
-// newAgentResponse creates an AgentResponse bound to the provided channel.
-func newAgentResponse(ch chan AgentEvent, pastMessages []types.Message) *AgentResponse {
+// newAgentResponse creates an AgentResponse for a run governed by ctx.
+func newAgentResponse(runID string, ctx context.Context, cancel context.CancelFunc, pastMessages []types.Message) *AgentResponse {
 	return &AgentResponse{
-		events:       ch,
-		pastEvents:   []AgentEvent{},
-		pastMessages: pastMessages,
-		closed:       false,
+		runID:      runID,
+		events:     make(chan AgentEvent, 10),
+		done:       make(chan struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+		pastEvents: []AgentEvent{},
+		result:     runResult{messages: pastMessages, status: StatusRunning},
 	}
 }
 
-// Stream returns a channel that yields events in real time while also
-// accumulating them for later retrieval.
-func (ar *AgentResponse) Stream() <-chan AgentEvent {
-	outchan := make(chan AgentEvent, 10)
+// finish records the outcome of the run and closes the event bus.
+func (ar *AgentResponse) finish(result runResult) {
+	ar.mu.Lock()
+	ar.result = result
+	ar.mu.Unlock()
+
+	close(ar.events)
+	close(ar.done)
+}
+
+// send hands an event to the event bus, giving up once the run has been
+// cancelled so an abandoned consumer cannot block it forever. The run's final
+// event is still sent then if the bus has room, so that consumers learn how
+// a stopped run ended.
+func (ar *AgentResponse) send(event AgentEvent) bool {
+	select {
+	case ar.events <- event:
+		return true
+	case <-ar.ctx.Done():
+	}
+	if _, ok := event.RunCompleted(); ok {
+		select {
+		case ar.events <- event:
+			return true
+		default:
+		}
+	}
+	return false
+}
+
+// startStream creates the consumer-facing stream if needed and reports
+// whether this call created it.
+func (ar *AgentResponse) startStream() bool {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	if ar.stream != nil {
+		return false
+	}
+
+	ar.stream = make(chan AgentEvent, 10)
 	go func() {
-		defer close(outchan)
+		defer close(ar.stream)
+		// release the run's context once every event has been handed over
+		defer ar.cancel()
 		for event := range ar.events {
-			if ar.closed {
-				return
-			}
+			ar.mu.Lock()
 			ar.pastEvents = append(ar.pastEvents, event)
-			outchan <- event
+			ar.mu.Unlock()
+
+			// after Stop, events are recorded but no longer delivered, save
+			// for the run's outcome if the consumer has room for it
+			select {
+			case ar.stream <- event:
+			case <-ar.ctx.Done():
+				if _, ok := event.RunCompleted(); ok {
+					select {
+					case ar.stream <- event:
+					default:
+					}
+				}
+			}
 		}
 	}()
-	return outchan
+	return true
 }
 
-// waitForStreamCompletion drains the event stream until it closes.
+// Stream returns a channel that yields events in real time while also
+// accumulating them for later retrieval. Every call returns the same channel,
+// which is closed once the run has finished.
+func (ar *AgentResponse) Stream() <-chan AgentEvent {
+	ar.startStream()
+	return ar.stream
+}
+
+// waitForStreamCompletion blocks until the run has finished, draining the
+// event stream unless a consumer is already reading it.
 func (ar *AgentResponse) waitForStreamCompletion() {
-	for range ar.Stream() {
+	if ar.startStream() {
+		for range ar.stream {
+		}
 	}
+	<-ar.done
+}
+
+// RunID returns the identifier carried by the run's events.
+func (ar *AgentResponse) RunID() string {
+	return ar.runID
 }
 
 // Response returns the last message produced in the conversation.
 func (ar *AgentResponse) Response() types.Message {
 	allMessages := ar.FinalConversation()
-	lastMessage := allMessages[len(allMessages)-1]
-
-	return lastMessage
+	if len(allMessages) == 0 {
+		return types.Message{}
+	}
+	return allMessages[len(allMessages)-1]
 }
 
 // FinalConversation waits for streaming to finish and returns every message
 // that occurred during the run.
 func (ar *AgentResponse) FinalConversation() []types.Message {
 	ar.waitForStreamCompletion()
-	finalMessages := make([]types.Message, 0, len(ar.pastMessages)+len(ar.pastEvents))
-	finalMessages = append(finalMessages, ar.pastMessages...)
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	finalMessages := make([]types.Message, 0, len(ar.result.messages))
+	finalMessages = append(finalMessages, ar.result.messages...)
 	return finalMessages
 }
 
-func (ar *AgentResponse) Stop() {
-	// closes the event channel if not already
+// Status waits for the run to finish and reports how it ended.
+func (ar *AgentResponse) Status() RunStatus {
+	ar.waitForStreamCompletion()
 	ar.mu.Lock()
 	defer ar.mu.Unlock()
-	if !ar.closed {
-		ar.closed = true
-	}
+	return ar.result.status
+}
+
+// Err waits for the run to finish and returns the error that ended it, if
+// any. Cancelled runs report the context's error.
+func (ar *AgentResponse) Err() error {
+	ar.waitForStreamCompletion()
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	return ar.result.err
+}
+
+// Usage waits for the run to finish and returns the tokens consumed by all of
+// its LLM calls, as reported by the providers.
+func (ar *AgentResponse) Usage() types.Usage {
+	ar.waitForStreamCompletion()
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	return ar.result.usage
+}
+
+// UsageByAgent waits for the run to finish and returns the tokens consumed by
+// each agent that took part in it.
+func (ar *AgentResponse) UsageByAgent() map[string]types.Usage {
+	ar.waitForStreamCompletion()
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	return maps.Clone(ar.result.agentUsage)
+}
+
+// Cost waits for the run to finish and returns its estimated cost in USD,
+// based on the pricing table and the models' Pricing overrides.
+func (ar *AgentResponse) Cost() float64 {
+	ar.waitForStreamCompletion()
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	return ar.result.cost
+}
+
+// HandoffChain waits for the run to finish and returns the names of the
+// agents that were active, in order, starting with the agent the run was
+// started with. Returns to a calling agent appear as handoffs to it.
+func (ar *AgentResponse) HandoffChain() []string {
+	ar.waitForStreamCompletion()
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	return slices.Clone(ar.result.chain)
+}
+
+// Stop cancels the run: the in-flight LLM request is aborted, pending tool
+// calls are skipped and the event stream is closed. It does not wait for the
+// run to wind down; use Status to do so.
+func (ar *AgentResponse) Stop() {
+	ar.cancel()
 }
@@ -2,16 +2,23 @@ package runner
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"reflect"
 	"slices"
 	"strings"
+	"time"
 
+	"github.com/logkn/agents-go/internal/history"
+	"github.com/logkn/agents-go/internal/providers"
+	"github.com/logkn/agents-go/internal/tools"
+	"github.com/logkn/agents-go/internal/tracing"
 	"github.com/logkn/agents-go/internal/types"
 	"github.com/logkn/agents-go/internal/utils"
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
 )
 
 // ToolResult represents the output of a tool call executed by an agent.
@@ -32,22 +39,6 @@ type Input struct {
 	OfMessages []types.Message
 }
 
-// findHandoffByToolName searches for a handoff that matches the given tool name
-func findHandoffByToolName[Context any](agent types.Agent[Context], toolName string) *types.Handoff[Context] {
-	for _, handoff := range agent.Handoffs {
-		if handoff.ToolName == toolName ||
-			(handoff.ToolName == "" && strings.HasPrefix(toolName, "transfer_to_")) {
-			return &handoff
-		}
-	}
-	return nil
-}
-
-// isHandoffTool checks if the given tool name corresponds to a handoff tool
-func isHandoffTool[Context any](agent types.Agent[Context], toolName string) bool {
-	return findHandoffByToolName(agent, toolName) != nil
-}
-
 // Run executes the agent against the provided input and returns an
 // AgentResponse for consuming the results.
 // Run executes the agent and streams events back through an AgentResponse.
@@ -55,12 +46,20 @@ func isHandoffTool[Context any](agent types.Agent[Context], toolName string) boo
 // history. Otherwise a new conversation is started with input.OfString as the
 // user prompt.
 // The globalContext parameter provides shared state accessible to all tools during execution.
-func Run[Context any](agent types.Agent[Context], input Input, ctx *Context) (AgentResponse, error) {
+// runCtx governs the whole run: cancelling it, or calling Stop on the
+// response, aborts the in-flight LLM request and skips pending tool calls.
+// Options set per-run limits; exceeding one ends the run with a typed error.
+func Run[Context any](runCtx context.Context, agent types.Agent[Context], input Input, ctx *Context, opts ...RunOption) (*AgentResponse, error) {
 	logger := agent.Logger
 	if logger == nil {
 		logger = slog.Default()
 	}
 
+	config := RunConfig{}
+	if err := config.Apply(opts...); err != nil {
+		return nil, err
+	}
+
 	logger.Info("starting agent run",
 		"agent_name", agent.Name,
 		"model", agent.Model.Model,
@@ -71,14 +70,37 @@ func Run[Context any](agent types.Agent[Context], input Input, ctx *Context) (Ag
 	if agent.Hooks != nil && agent.Hooks.BeforeRun != nil {
 		if err := agent.Hooks.BeforeRun(ctx); err != nil {
 			logger.Error("BeforeRun hook failed", "error", err)
-			return AgentResponse{}, fmt.Errorf("BeforeRun hook failed: %w", err)
+			return nil, fmt.Errorf("BeforeRun hook failed: %w", err)
 		}
 	}
 
+	messages, history, err := startingMessages(runCtx, input, config, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// check that the model exists, once per top-level run: nested runs, such
+	// as agents run as tools, are part of a run that already started
+	if parentRunFromContext(runCtx) == nil {
+		if validator, ok := providers.For(agent.Model).(types.ModelValidator); ok {
+			if err := validator.ValidateModel(runCtx, agent.Model); err != nil {
+				logger.Error("model validation failed", "model", agent.Model.Model, "error", err)
+				return nil, err
+			}
+		}
+	}
+
+	state := RunState{RunID: newRunID(), Agent: agent.Name, Messages: messages}
+	return start(runCtx, agent, nil, state, history, ctx, config, logger)
+}
+
+// startingMessages returns the conversation a run on input starts from and
+// how many of its leading messages were loaded from the run's session.
+func startingMessages(runCtx context.Context, input Input, config RunConfig, logger *slog.Logger) ([]types.Message, int, error) {
 	var messages []types.Message
 	switch {
 	case len(input.OfMessages) > 0:
-		messages = input.OfMessages
+		messages = slices.Clone(input.OfMessages)
 		logger.Debug("using existing conversation", "message_count", len(input.OfMessages))
 	default:
 		messages = []types.Message{
@@ -87,214 +109,623 @@ func Run[Context any](agent types.Agent[Context], input Input, ctx *Context) (Ag
 		logger.Debug("starting new conversation", "user_prompt", input.OfString)
 	}
 
-	var client openai.Client
-	if agent.Model.BaseURL != "" {
-		logger.Debug("using custom base URL", "base_url", agent.Model.BaseURL)
-		client = openai.NewClient(
-			option.WithBaseURL(agent.Model.BaseURL),
-		)
+	// continue the session's conversation
+	history := 0
+	if config.Session != nil {
+		past, err := config.Session.Load(runCtx)
+		if err != nil {
+			logger.Error("failed to load session", "error", err)
+			return nil, 0, fmt.Errorf("failed to load session: %w", err)
+		}
+		history = len(past)
+		messages = append(past, messages...)
+		logger.Debug("loaded session history", "message_count", history)
+	}
+	return messages, history, nil
+}
+
+// runContext returns the context governing a run, ending it once the run's
+// Timeout has elapsed.
+func runContext(runCtx context.Context, config RunConfig) (context.Context, context.CancelFunc) {
+	if config.Timeout > 0 {
+		return context.WithDeadlineCause(runCtx, time.Now().Add(config.Timeout),
+			fmt.Errorf("%w: %s", ErrDeadlineExceeded, config.Timeout))
+	}
+	return context.WithCancel(runCtx)
+}
+
+// start launches the goroutine driving a run from state, with callers
+// waiting for agent to return. The first history messages are already stored
+// in the run's session.
+func start[Context any](runCtx context.Context, agent types.Agent[Context], callers []handoffFrame[Context], state RunState, history int, ctx *Context, config RunConfig, logger *slog.Logger) (*AgentResponse, error) {
+	runCtx, cancel := runContext(runCtx, config)
+	messages := slices.Clone(state.Messages)
+	agentResponse := newAgentResponse(state.RunID, runCtx, cancel, messages)
+	parent := parentRunFromContext(runCtx)
+	parent.nest(agentResponse)
+
+	exec := &execution[Context]{
+		runID:      state.RunID,
+		started:    time.Now(),
+		runCtx:     runCtx,
+		config:     config,
+		agent:      agent,
+		ctx:        ctx,
+		logger:     logger,
+		messages:   messages,
+		history:    history,
+		response:   agentResponse,
+		parent:     parent,
+		view:       slices.Clone(state.AgentView),
+		viewFrom:   state.ViewFrom,
+		callers:    callers,
+		chain:      slices.Clone(state.HandoffChain),
+		pending:    slices.Clone(state.PendingToolCalls),
+		turns:      state.Turns,
+		toolCalls:  state.ToolCalls,
+		usage:      state.Usage,
+		agentUsage: maps.Clone(state.AgentUsage),
+		cost:       state.CostUSD,
+	}
+	if err := exec.setAgent(agent); err != nil {
+		cancel()
+		return nil, err
+	}
+	if len(exec.chain) == 0 {
+		exec.chain = []string{agent.Name}
+	}
+
+	// a run without its own tracer joins the trace of its caller
+	parentSpan := tracing.SpanFromContext(runCtx)
+	if config.Tracer != nil || parentSpan == nil {
+		exec.span = config.Tracer.Start(tracing.KindRun, agent.Name)
 	} else {
-		logger.Debug("using OpenAI API")
-		client = openai.NewClient()
+		exec.span = parentSpan.StartChild(tracing.KindRun, agent.Name)
+	}
+	exec.span.SetInput(lastUserInput(messages))
+	exec.span.SetAttribute("run_id", state.RunID)
+	exec.span.SetAttribute("agent", agent.Name)
+	exec.span.SetAttribute("model", agent.Model.Model)
+	exec.runCtx = tracing.ContextWithSpan(runCtx, exec.span)
+
+	go exec.run()
+
+	logger.Debug("agent run initiated successfully")
+	return agentResponse, nil
+}
+
+// execution holds the state of a single run while its goroutine drives the
+// conversation.
+type execution[Context any] struct {
+	runID    string
+	started  time.Time
+	runCtx   context.Context
+	config   RunConfig
+	agent    types.Agent[Context]
+	ctx      *Context
+	logger   *slog.Logger
+	messages []types.Message
+	// history counts the leading messages loaded from the session.
+	history         int
+	response        *AgentResponse
+	span            *tracing.ActiveSpan
+	provider        types.Provider
+	toolDefinitions []tools.Definition
+	// output is set when the active agent answers with structured output.
+	output         *outputSpec
+	outputAttempts int
+	finalOutput    any
+
+	// view is what the active agent was shown of the first viewFrom
+	// messages when it received a handoff; nil if it sees the transcript.
+	view     []types.Message
+	viewFrom int
+	// callers are the agents waiting for a handoff to return, innermost
+	// last.
+	callers []handoffFrame[Context]
+	// chain lists the agents that have been active, in order.
+	chain []string
+	// parent is the run this one is nested in, if any.
+	parent *parentRun
+	// pending are tool calls left unanswered by an interrupted run, run
+	// before the model is called again.
+	pending []types.ToolCall
+	// interrupted are the tool calls skipped because the run was cancelled.
+	interrupted []types.ToolCall
+
+	// counters checked against the run's limits
+	turns     int
+	toolCalls int
+	usage     types.Usage
+
+	agentUsage map[string]types.Usage
+	cost       float64
+}
+
+// setAgent makes agent the active agent of the run.
+func (e *execution[Context]) setAgent(agent types.Agent[Context]) error {
+	if agent.Logger == nil {
+		agent.Logger = e.logger
+	}
+	provider := providers.For(agent.Model)
+	output, err := newOutputSpec(agent.OutputType, provider)
+	if err != nil {
+		return fmt.Errorf("agent %s: %w", agent.Name, err)
 	}
-	// check that the model exists
-	// if _, err := client.Models.Get(context.TODO(), agent.Model.Model); err != nil {
-	// 	return AgentResponse{}, err
-	// }
 
-	allTools := agent.AllTools()
-	openAITools := make([]openai.ChatCompletionToolParam, len(allTools))
-	for i, tool := range allTools {
-		openAITools[i] = tool.ToOpenAITool()
+	definitions, err := agent.ToolDefinitions()
+	if err != nil {
+		return fmt.Errorf("agent %s: %w", agent.Name, err)
+	}
+	if back := e.returnHandoff(); back != nil {
+		definition, err := back.Definition()
+		if err != nil {
+			return fmt.Errorf("agent %s: %w", agent.Name, err)
+		}
+		definitions = append(definitions, definition)
+	}
+	if output != nil && output.viaTool {
+		definitions = append(definitions, output.toolDefinition())
 	}
 
-	eventChannel := make(chan AgentEvent, 10)
-	agentResponse := newAgentResponse(eventChannel, messages)
+	e.agent = agent
+	e.provider = provider
+	e.output = output
+	e.outputAttempts = 0
+	e.toolDefinitions = definitions
+	return nil
+}
 
-	go func() {
-		for {
-			logger.Debug("sending request to LLM", "message_count", len(messages))
-			openaiMessages := utils.MapSlice(messages, types.Message.ToOpenAI)
-			// insert the instructions at the beginning of the openaiMessages
-			instructions, err := agent.Instructions.ToString(ctx)
-			if err != nil {
-				panic(err)
-			}
-			systemMessage := types.NewSystemMessage(instructions)
-			openaiMessages = slices.Insert(openaiMessages, 0, systemMessage.ToOpenAI())
-
-			params := openai.ChatCompletionNewParams{
-				Messages:    openaiMessages,
-				Model:       agent.Model.Model,
-				Tools:       openAITools,
-				Temperature: openai.Float(0.6),
-			}
-			stream := client.Chat.Completions.NewStreaming(context.TODO(), params)
-			acc := openai.ChatCompletionAccumulator{}
-			tokenCount := 0
-			for stream.Next() {
-				chunk := stream.Current()
-				acc.AddChunk(chunk)
-
-				if len(chunk.Choices) > 0 {
-					token := chunk.Choices[0].Delta.Content
-					if token != "" {
-						tokenCount++
-					}
-					eventChannel <- tokenEvent(token)
-				}
-			}
-			logger.Debug("received response from LLM", "tokens_received", tokenCount)
-			choices := acc.Choices
-			// if no choices, break the loop
-			if len(choices) == 0 {
-				logger.Debug("no choices returned from LLM, ending conversation")
-				break
-			}
-			openaimsg := choices[0].Message
-
-			// check for refusals
-			if openaimsg.Refusal != "" {
-				err := fmt.Errorf("LLM refusal: %s", openaimsg.Refusal)
-				logger.Error("LLM refused to respond", "refusal", openaimsg.Refusal)
-				eventChannel <- errorEvent(err)
-				return
-			}
+// emit stamps an event with the run's position and delivers it to the
+// response. It gives up once the run has been cancelled so an abandoned
+// consumer cannot block the run forever.
+func (e *execution[Context]) emit(event AgentEvent) bool {
+	event.RunID = e.runID
+	event.Turn = e.turns
+	event.Agent = e.agent.Name
+	return e.deliver(event)
+}
+
+// deliver hands a stamped event to the response and, for a nested run, to
+// the run it is nested in.
+func (e *execution[Context]) deliver(event AgentEvent) bool {
+	if !e.response.send(event) {
+		return false
+	}
+	e.parent.forward(event)
+	return true
+}
+
+// conversation returns the messages the active agent sees: the transcript,
+// or after a handoff, the view it was given followed by the messages added
+// since.
+func (e *execution[Context]) conversation() []types.Message {
+	if e.view == nil {
+		return e.messages
+	}
+	messages := make([]types.Message, 0, len(e.view)+len(e.messages)-e.viewFrom)
+	messages = append(messages, e.view...)
+	return append(messages, e.messages[e.viewFrom:]...)
+}
 
-			msg := types.AssistantMessageFromOpenAI(openaimsg, agent.Name)
-			messages = append(messages, msg)
+// appendMessage records a message in the conversation and emits it.
+func (e *execution[Context]) appendMessage(msg types.Message) {
+	e.messages = append(e.messages, msg)
+	e.emit(messageEvent(msg))
+}
 
-			eventChannel <- messageEvent(msg)
+// run is the body of the run goroutine. It always closes the event stream.
+func (e *execution[Context]) run() {
+	e.emit(runStartedEvent(RunStartedEvent{
+		Model:    e.agent.Model.Model,
+		Messages: len(e.messages),
+	}))
+
+	err := e.loop()
+	if saveErr := e.saveSession(); saveErr != nil {
+		e.logger.Error("failed to save session", "error", saveErr)
+		if err == nil {
+			err = saveErr
+		}
+	}
 
-			toolcalls := msg.ToolCalls
+	status := StatusCompleted
+	switch {
+	case errors.Is(context.Cause(e.runCtx), ErrDeadlineExceeded):
+		status = StatusFailed
+		err = context.Cause(e.runCtx)
+		e.logger.Error("agent run exceeded its deadline", "timeout", e.config.Timeout)
+		e.emit(errorEvent(err))
+	case e.runCtx.Err() != nil:
+		status = StatusCancelled
+		err = e.runCtx.Err()
+		e.logger.Info("agent run cancelled")
+	case err != nil:
+		status = StatusFailed
+		e.emit(errorEvent(err))
+	}
 
-			if len(toolcalls) == 0 {
-				logger.Info("assistant response completed", "content_length", len(msg.Content))
+	// Execute AfterRun hook before stopping
+	agent := e.agent
+	if agent.Hooks != nil && agent.Hooks.AfterRun != nil {
+		// Get the final response content for the hook
+		finalResponse := ""
+		for i := len(e.messages) - 1; i >= 0; i-- {
+			if e.messages[i].Role == types.Assistant && e.messages[i].Content != "" {
+				finalResponse = e.messages[i].Content
 				break
 			}
+		}
+		if err := agent.Hooks.AfterRun(e.ctx, finalResponse); err != nil {
+			e.logger.Error("AfterRun hook failed", "error", err)
+		}
+	}
 
-			logger.Info("processing tool calls", "tool_call_count", len(toolcalls))
-
-			for _, toolcall := range toolcalls {
-				funcname := toolcall.Name
-				logger.Debug("executing tool",
-					"tool_name", funcname,
-					"tool_call_id", toolcall.ID,
-					"args_length", len(toolcall.Args))
-
-				// Check if this is a handoff tool
-				if handoff := findHandoffByToolName(agent, funcname); handoff != nil {
-					logger.Info("executing handoff",
-						"from_agent", agent.Name,
-						"to_agent", handoff.Agent.Name,
-						"tool_call_id", toolcall.ID)
-
-					// Parse handoff arguments to get the prompt
-					var args struct {
-						Prompt string `json:"prompt"`
-					}
-					if err := json.Unmarshal([]byte(toolcall.Args), &args); err != nil {
-						logger.Error("failed to parse handoff arguments", "error", err)
-						continue
-					}
-
-					// Emit handoff event
-					eventChannel <- handoffEvent(HandoffEvent{
-						FromAgent: agent.Name,
-						ToAgent:   handoff.Agent.Name,
-						Prompt:    args.Prompt,
-					})
-
-					// Create tool result message for the handoff
-					toolmessage := types.NewToolMessage(toolcall.ID, "Transferring to "+handoff.Agent.Name+" agent")
-					messages = append(messages, toolmessage)
-					eventChannel <- messageEvent(toolmessage)
-
-					// Switch to the handoff agent and continue with the new prompt
-					agent = *handoff.Agent
-					if agent.Logger == nil {
-						agent.Logger = logger
-					}
-
-					// Add the handoff prompt as a user message
-					messages = append(messages, types.NewUserMessage(args.Prompt))
-
-					// Update tool list for the new agent
-					allTools = agent.AllTools()
-					openAITools = make([]openai.ChatCompletionToolParam, len(allTools))
-					for i, tool := range allTools {
-						openAITools[i] = tool.ToOpenAITool()
-					}
-
-					logger.Info("handoff completed", "new_agent", agent.Name)
-					continue
-				}
-
-				// Regular tool execution
-				toolFound := false
-				for _, tool := range allTools {
-					if tool.CompleteName() == funcname {
-						toolFound = true
-
-						// Execute BeforeToolCall hook
-						if agent.Hooks != nil && agent.Hooks.BeforeToolCall != nil {
-							if err := agent.Hooks.BeforeToolCall(ctx, funcname, toolcall.Args); err != nil {
-								logger.Error("BeforeToolCall hook failed", "error", err, "tool_name", funcname)
-								continue
-							}
-						}
-
-						// Use contextual execution if global context is provided, otherwise use regular execution
-						result := tool.RunOnArgs(toolcall.Args, ctx)
-
-						// Execute AfterToolCall hook
-						if agent.Hooks != nil && agent.Hooks.AfterToolCall != nil {
-							if err := agent.Hooks.AfterToolCall(ctx, funcname, result); err != nil {
-								logger.Error("AfterToolCall hook failed", "error", err, "tool_name", funcname)
-							}
-						}
-
-						logger.Info("tool execution completed",
-							"tool_name", funcname,
-							"tool_call_id", toolcall.ID)
-
-						toolmessage := types.NewToolMessage(toolcall.ID, result)
-						messages = append(messages, toolmessage)
-						eventChannel <- messageEvent(toolmessage)
-						eventChannel <- toolEvent(ToolResult{
-							Name:       tool.CompleteName(),
-							Content:    result,
-							ToolCallID: toolcall.ID,
-						})
-						break
-					}
-				}
-				if !toolFound {
-					logger.Error("tool not found", "tool_name", funcname)
-				}
-			}
+	e.endSpan(status, err)
+	e.emit(runCompletedEvent(RunCompletedEvent{
+		Status:   status,
+		Err:      err,
+		Usage:    e.usage,
+		CostUSD:  e.cost,
+		Duration: time.Since(e.started),
+	}))
+	e.response.finish(runResult{
+		messages:   e.messages,
+		state:      e.state(),
+		chain:      e.chain,
+		output:     e.finalOutput,
+		usage:      e.usage,
+		agentUsage: e.agentUsage,
+		cost:       e.cost,
+		status:     status,
+		err:        err,
+	})
+}
+
+// endSpan finishes the run's span with its outcome.
+func (e *execution[Context]) endSpan(status RunStatus, err error) {
+	span := e.span
+	if len(e.messages) > 0 {
+		span.SetOutput(e.messages[len(e.messages)-1].Content)
+	}
+	span.SetUsage(e.usage)
+	span.SetAttribute("status", status.String())
+	span.SetAttribute("turns", e.turns)
+	span.SetAttribute("cost_usd", e.cost)
+	span.SetError(err)
+	span.End()
+}
+
+// saveSession stores the messages added by the run in its session, even if
+// the run was cancelled. Like the run's state, it leaves out the placeholder
+// results of interrupted tool calls: their real results are saved once the
+// run is resumed.
+func (e *execution[Context]) saveSession() error {
+	session := e.config.Session
+	if session == nil || len(e.messages) <= e.history {
+		return nil
+	}
+	added := e.withoutInterrupted(e.messages[e.history:])
+	if len(added) == 0 {
+		return nil
+	}
+	if err := session.Append(context.WithoutCancel(e.runCtx), added...); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// loop alternates between LLM calls and tool execution until the assistant
+// answers without tool calls, the run is cancelled or an error occurs.
+func (e *execution[Context]) loop() error {
+	if len(e.pending) > 0 {
+		e.logger.Info("resuming pending tool calls", "tool_call_count", len(e.pending))
+		toolcalls := e.pending
+		e.pending = nil
+		if err := e.runToolCalls(toolcalls); err != nil {
+			return err
+		}
+	}
+
+	for {
+		if err := e.runCtx.Err(); err != nil {
+			return err
+		}
+		if limit := e.config.MaxTurns; limit > 0 && e.turns >= limit {
+			return fmt.Errorf("%w: limit of %d turns reached", ErrMaxTurnsExceeded, limit)
+		}
+		e.turns++
+		e.emit(turnStartedEvent(TurnStartedEvent{Messages: len(e.messages)}))
+
+		var msg *types.Message
+		var err error
+		if e.turns == 1 {
+			msg, err = e.callModelGuarded()
+		} else {
+			msg, err = e.callModel(e.runCtx)
+		}
+		if err != nil {
+			return err
 		}
 
-		// Execute AfterRun hook before stopping
-		if agent.Hooks != nil && agent.Hooks.AfterRun != nil {
-			// Get the final response content for the hook
-			finalResponse := ""
-			if len(messages) > 0 {
-				for i := len(messages) - 1; i >= 0; i-- {
-					if messages[i].Role == types.Assistant && messages[i].Content != "" {
-						finalResponse = messages[i].Content
-						break
-					}
-				}
+		if e.output != nil && e.output.isAnswer(*msg) {
+			done, err := e.handleOutput(*msg)
+			if err != nil {
+				return err
 			}
-			if err := agent.Hooks.AfterRun(ctx, finalResponse); err != nil {
-				logger.Error("AfterRun hook failed", "error", err)
+			if done {
+				return e.checkOutput()
 			}
+			continue
+		}
+
+		e.appendMessage(*msg)
+
+		toolcalls := msg.ToolCalls
+		if len(toolcalls) == 0 {
+			e.logger.Info("assistant response completed", "content_length", len(msg.Content))
+			return e.checkOutput()
+		}
+
+		e.logger.Info("processing tool calls", "tool_call_count", len(toolcalls))
+		if err := e.runToolCalls(toolcalls); err != nil {
+			return err
+		}
+	}
+}
+
+// callModel requests one assistant turn from the provider, retrying transient
+// failures according to the model's retry policy. ctx governs the request.
+func (e *execution[Context]) callModel(ctx context.Context) (*types.Message, error) {
+	conversation, err := e.compactHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := e.agent.Model.Retry
+	maxAttempts := policy.Attempts()
+	for attempt := 1; ; attempt++ {
+		msg, err := e.attemptCompletion(ctx, conversation)
+		retryable := providers.IsRetryable(err) || errors.Is(err, ErrRequestTimeout)
+		if err == nil || attempt >= maxAttempts || ctx.Err() != nil || !retryable {
+			return msg, err
 		}
 
-		agentResponse.Stop()
+		delay := max(policy.Backoff(attempt), providers.RetryAfter(err))
+		e.logger.Warn("LLM request failed, retrying",
+			"attempt", attempt,
+			"max_attempts", maxAttempts,
+			"delay", delay,
+			"error", err)
+		e.emit(retryEvent(RetryEvent{
+			Attempt:     attempt,
+			MaxAttempts: maxAttempts,
+			Delay:       delay,
+			Err:         err,
+		}))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// compactHistory applies the agent's history strategy to the conversation it
+// sees, reporting when it changed what the model will see.
+func (e *execution[Context]) compactHistory(ctx context.Context) ([]types.Message, error) {
+	messages := e.conversation()
+	strategy := e.agent.History
+	if strategy == nil {
+		return messages, nil
+	}
+	compacted, err := strategy.Compact(ctx, messages)
+	if err != nil {
+		e.logger.Error("history compaction failed", "error", err)
+		return nil, fmt.Errorf("failed to compact history: %w", err)
+	}
+	if reflect.DeepEqual(compacted, messages) {
+		return messages, nil
+	}
+
+	event := CompactionEvent{
+		Agent:          e.agent.Name,
+		MessagesBefore: len(messages),
+		MessagesAfter:  len(compacted),
+		TokensBefore:   history.EstimateTokens(messages),
+		TokensAfter:    history.EstimateTokens(compacted),
+	}
+	e.logger.Info("compacted conversation history",
+		"messages_before", event.MessagesBefore,
+		"messages_after", event.MessagesAfter,
+		"tokens_before", event.TokensBefore,
+		"tokens_after", event.TokensAfter)
+	e.emit(compactionEvent(event))
+	return compacted, nil
+}
+
+// attemptCompletion makes one LLM request, bounded by the model's
+// RequestTimeout.
+func (e *execution[Context]) attemptCompletion(ctx context.Context, conversation []types.Message) (msg *types.Message, err error) {
+	span := e.span.StartChild(tracing.KindGeneration, e.agent.Model.Model)
+	span.SetAttribute("agent", e.agent.Name)
+	defer func() {
+		span.SetError(err)
+		span.End()
 	}()
+	ctx = tracing.ContextWithSpan(ctx, span)
 
-	logger.Debug("agent run initiated successfully")
-	return *agentResponse, nil
+	timeout := e.agent.Model.RequestTimeout
+	if timeout <= 0 {
+		return e.streamCompletion(ctx, conversation)
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, ErrRequestTimeout)
+	defer cancel()
+	msg, err = e.streamCompletion(ctx, conversation)
+	if err != nil && context.Cause(ctx) == ErrRequestTimeout {
+		err = fmt.Errorf("%w after %s: %w", ErrRequestTimeout, timeout, err)
+	}
+	return msg, err
+}
+
+// streamCompletion streams a single attempt at an assistant turn, sending
+// conversation as the message history.
+func (e *execution[Context]) streamCompletion(ctx context.Context, conversation []types.Message) (*types.Message, error) {
+	logger := e.logger
+
+	logger.Debug("sending request to LLM", "message_count", len(conversation))
+	// insert the instructions at the beginning of the messages
+	instructions, err := e.agent.Instructions.Render(e.ctx, &e.agent)
+	if err != nil {
+		logger.Error("failed to render instructions", "error", err)
+		return nil, fmt.Errorf("%w for agent %s: %w", ErrInstructions, e.agent.Name, err)
+	}
+	systemMessage := types.NewSystemMessage(instructions)
+	requestMessages := slices.Insert(slices.Clone(conversation), 0, systemMessage)
+
+	request := types.CompletionRequest{
+		Model:    e.agent.Model,
+		Messages: requestMessages,
+		Tools:    e.toolDefinitions,
+	}
+	if e.output != nil && !e.output.viaTool {
+		request.ResponseFormat = &e.output.format
+	}
+	span := tracing.SpanFromContext(ctx)
+	span.SetInput(utils.AsString(requestMessages))
+	e.emit(llmRequestSentEvent(LLMRequestSentEvent{
+		Model:    e.agent.Model.Model,
+		Messages: len(requestMessages),
+		Tools:    len(request.Tools),
+	}))
+	stream, err := e.provider.Stream(ctx, request)
+	if err != nil {
+		logger.Error("failed to start completion stream", "error", err)
+		return nil, err
+	}
+	defer stream.Close()
+
+	acc := types.CompletionAccumulator{}
+	deltas := toolCallDeltas{}
+	// models such as qwen write their reasoning inline between <think> tags
+	splitter := utils.ThinkSplitter{}
+	for stream.Next() {
+		chunk := stream.Current()
+		content, thinking := splitter.Split(chunk.Content)
+		chunk.Content = content
+		chunk.Reasoning += thinking
+		acc.AddChunk(chunk)
+
+		e.emitText(chunk)
+		for _, delta := range chunk.ToolCalls {
+			e.emit(toolCallDeltaEvent(deltas.add(delta)))
+		}
+	}
+	if content, thinking := splitter.Flush(); content != "" || thinking != "" {
+		chunk := types.CompletionChunk{Content: content, Reasoning: thinking}
+		acc.AddChunk(chunk)
+		e.emitText(chunk)
+	}
+	if err := stream.Err(); err != nil {
+		logger.Error("completion stream failed", "error", err)
+		return nil, err
+	}
+	if acc.Usage != nil {
+		e.recordUsage(*acc.Usage)
+		span.SetUsage(*acc.Usage)
+	}
+	if acc.Empty() {
+		logger.Error("LLM returned an empty response")
+		return nil, ErrEmptyResponse
+	}
+
+	// check for refusals
+	if refusal := acc.Refusal(); refusal != "" {
+		logger.Error("LLM refused to respond", "refusal", refusal)
+		return nil, fmt.Errorf("LLM refusal: %s", refusal)
+	}
+
+	msg := acc.Message(e.agent.Name)
+	span.SetOutput(utils.AsString(msg))
+	if limit := e.config.MaxTotalTokens; limit > 0 && e.usage.TotalTokens > limit {
+		// keep the answer that crossed the budget in the transcript
+		e.appendMessage(msg)
+		e.skipToolCalls(msg.ToolCalls, ErrMaxTokensExceeded)
+		return nil, fmt.Errorf("%w: used %d of %d tokens", ErrMaxTokensExceeded, e.usage.TotalTokens, limit)
+	}
+	return &msg, nil
+}
+
+// emitText reports the reasoning and answer text of a chunk.
+func (e *execution[Context]) emitText(chunk types.CompletionChunk) {
+	if chunk.Reasoning != "" {
+		e.emit(reasoningTokenEvent(chunk.Reasoning))
+	}
+	if chunk.Content != "" {
+		e.emit(tokenEvent(chunk.Content))
+	}
+}
+
+// toolCallDeltas follows the tool calls streamed in one completion, so each
+// fragment is reported with its call's ID, name and arguments so far.
+type toolCallDeltas map[int]*toolCallProgress
+
+type toolCallProgress struct {
+	event ToolCallDeltaEvent
+	// args grows without copying what was received before
+	args strings.Builder
+}
+
+func (d toolCallDeltas) add(delta types.ToolCallDelta) ToolCallDeltaEvent {
+	call, ok := d[delta.Index]
+	if !ok {
+		call = &toolCallProgress{event: ToolCallDeltaEvent{Index: delta.Index}}
+		d[delta.Index] = call
+	}
+	if delta.ID != "" {
+		call.event.ToolCallID = delta.ID
+	}
+	if delta.Name != "" {
+		call.event.Name = delta.Name
+	}
+	call.args.WriteString(delta.Args)
+	call.event.ArgsDelta = delta.Args
+	call.event.Args = call.args.String()
+	return call.event
+}
+
+// recordUsage accounts for the tokens of one LLM call and reports them.
+func (e *execution[Context]) recordUsage(usage types.Usage) {
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	}
+	cost := e.agent.Model.Price().Cost(usage)
+
+	e.usage = e.usage.Add(usage)
+	if e.agentUsage == nil {
+		e.agentUsage = map[string]types.Usage{}
+	}
+	e.agentUsage[e.agent.Name] = e.agentUsage[e.agent.Name].Add(usage)
+	e.cost += cost
+
+	e.logger.Debug("received response from LLM",
+		"input_tokens", usage.InputTokens,
+		"output_tokens", usage.OutputTokens,
+		"cost_usd", cost)
+	e.emit(usageEvent(UsageEvent{
+		Agent:   e.agent.Name,
+		Model:   e.agent.Model.Model,
+		Usage:   usage,
+		CostUSD: cost,
+	}))
+}
+
+// newRunID returns a random identifier for a run.
+func newRunID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return "run_" + hex.EncodeToString(id)
 }
@@ -1,8 +1,20 @@
 package runner
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/logkn/agents-go/internal/history"
+	"github.com/logkn/agents-go/internal/providers"
+	"github.com/logkn/agents-go/internal/tools"
+	"github.com/logkn/agents-go/internal/tracing"
 	"github.com/logkn/agents-go/internal/types"
 )
 
@@ -36,3 +48,1621 @@ type testErr string
 func (e testErr) Error() string { return string(e) }
 
 var ErrTest = testErr("boom")
+
+// fakeProvider replays one scripted list of chunks per completion request.
+type fakeProvider struct {
+	turns    [][]types.CompletionChunk
+	requests []types.CompletionRequest
+}
+
+func (p *fakeProvider) Stream(_ context.Context, req types.CompletionRequest) (types.CompletionStream, error) {
+	p.requests = append(p.requests, req)
+	if len(p.turns) == 0 {
+		return &fakeStream{}, nil
+	}
+	turn := p.turns[0]
+	p.turns = p.turns[1:]
+	return &fakeStream{chunks: turn, pos: -1}, nil
+}
+
+type fakeStream struct {
+	chunks []types.CompletionChunk
+	pos    int
+}
+
+func (s *fakeStream) Next() bool {
+	s.pos++
+	return s.pos < len(s.chunks)
+}
+
+func (s *fakeStream) Current() types.CompletionChunk { return s.chunks[s.pos] }
+func (s *fakeStream) Err() error                     { return nil }
+func (s *fakeStream) Close() error                   { return nil }
+
+type echoArgs struct {
+	Text string `json:"text"`
+}
+
+func (e echoArgs) Run(ctx *struct{}) any { return "echo: " + e.Text }
+
+func TestRunWithProvider(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{
+			{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "echo", Args: `{"te`}}},
+			{ToolCalls: []types.ToolCallDelta{{Index: 0, Args: `xt":"hi"}`}}},
+		},
+		{{Content: "all "}, {Content: "done"}},
+	}}
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+	agent.WithTools(tools.NewTool("echo", "Echo text back.", tools.ToolArgs[struct{}](echoArgs{})))
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var toolOutput string
+	for event := range resp.Stream() {
+		if result, ok := event.ToolResult(); ok {
+			toolOutput = result.Content.(string)
+		}
+	}
+
+	if toolOutput != "echo: hi" {
+		t.Fatalf("unexpected tool output %q", toolOutput)
+	}
+	if answer := resp.Response().Content; answer != "all done" {
+		t.Fatalf("unexpected answer %q", answer)
+	}
+	if resp.Status() != StatusCompleted {
+		t.Fatalf("unexpected status %v", resp.Status())
+	}
+	if len(resp.FinalConversation()) != 4 {
+		t.Fatalf("expected the full conversation, got %d messages", len(resp.FinalConversation()))
+	}
+	if len(provider.requests) != 2 || provider.requests[0].Messages[0].Role != types.System {
+		t.Fatalf("expected two requests starting with the system prompt")
+	}
+	if len(provider.requests[1].Messages) != 4 {
+		t.Fatalf("expected tool call and result in second request, got %d messages", len(provider.requests[1].Messages))
+	}
+}
+
+// blockingProvider streams one token and then waits for cancellation.
+type blockingProvider struct{}
+
+func (blockingProvider) Stream(ctx context.Context, _ types.CompletionRequest) (types.CompletionStream, error) {
+	return &blockingStream{ctx: ctx}, nil
+}
+
+type blockingStream struct {
+	ctx  context.Context
+	sent bool
+}
+
+func (s *blockingStream) Next() bool {
+	if !s.sent {
+		s.sent = true
+		return true
+	}
+	<-s.ctx.Done()
+	return false
+}
+
+func (s *blockingStream) Current() types.CompletionChunk {
+	return types.CompletionChunk{Content: "partial"}
+}
+func (s *blockingStream) Err() error   { return s.ctx.Err() }
+func (s *blockingStream) Close() error { return nil }
+
+func TestRunStop(t *testing.T) {
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: blockingProvider{}})
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// lifecycle events come before the first token
+	stream := resp.Stream()
+	for event := range stream {
+		if event.OfToken != "" {
+			if event.OfToken != "partial" {
+				t.Fatalf("expected the first token before stopping")
+			}
+			break
+		}
+	}
+	resp.Stop()
+
+	done := make(chan struct{})
+	var last AgentEvent
+	go func() {
+		for event := range stream {
+			last = event
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("stream was not closed after Stop")
+	}
+
+	if resp.Status() != StatusCancelled {
+		t.Fatalf("expected cancelled status, got %v", resp.Status())
+	}
+	if !errors.Is(resp.Err(), context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", resp.Err())
+	}
+	if completed, ok := last.RunCompleted(); !ok || completed.Status != StatusCancelled {
+		t.Fatalf("expected the stream to close with a cancelled RunCompleted, got %+v", last)
+	}
+}
+
+// loopingProvider asks for the echo tool on every turn.
+type loopingProvider struct{}
+
+func (loopingProvider) Stream(context.Context, types.CompletionRequest) (types.CompletionStream, error) {
+	return &fakeStream{pos: -1, chunks: []types.CompletionChunk{
+		{ToolCalls: []types.ToolCallDelta{{ID: "call", Name: "echo", Args: `{"text":"again"}`}}},
+		{Usage: &types.Usage{TotalTokens: 10}},
+	}}, nil
+}
+
+func TestRunLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  RunOption
+		want error
+	}{
+		{"turns", WithMaxTurns(3), ErrMaxTurnsExceeded},
+		{"tool calls", WithMaxToolCalls(2), ErrMaxToolCallsExceeded},
+		{"tokens", WithMaxTotalTokens(25), ErrMaxTokensExceeded},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: loopingProvider{}})
+			agent.WithTools(tools.NewTool("echo", "Echo text back.", tools.ToolArgs[struct{}](echoArgs{})))
+
+			resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{}, test.opt)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var eventErr error
+			for event := range resp.Stream() {
+				if err, ok := event.Error(); ok {
+					eventErr = err
+				}
+			}
+			if !errors.Is(eventErr, test.want) {
+				t.Fatalf("expected error event %v, got %v", test.want, eventErr)
+			}
+			if !errors.Is(resp.Err(), test.want) || resp.Status() != StatusFailed {
+				t.Fatalf("expected failed run with %v, got %v (%v)", test.want, resp.Err(), resp.Status())
+			}
+			// every tool call must still be answered
+			calls, results := 0, 0
+			for _, msg := range resp.FinalConversation() {
+				calls += len(msg.ToolCalls)
+				if msg.Role == types.Tool {
+					results++
+				}
+			}
+			if calls != results {
+				t.Fatalf("%d tool calls but %d tool results", calls, results)
+			}
+		})
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: blockingProvider{}})
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{}, WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !errors.Is(resp.Err(), ErrDeadlineExceeded) || resp.Status() != StatusFailed {
+		t.Fatalf("expected deadline error, got %v (%v)", resp.Err(), resp.Status())
+	}
+}
+
+// slowArgs sleeps and records the peak number of concurrent executions.
+type slowArgs struct {
+	Text string `json:"text"`
+}
+
+var (
+	slowActive atomic.Int32
+	slowPeak   atomic.Int32
+)
+
+func (s slowArgs) Run(ctx *struct{}) any {
+	active := slowActive.Add(1)
+	defer slowActive.Add(-1)
+	for {
+		peak := slowPeak.Load()
+		if active <= peak || slowPeak.CompareAndSwap(peak, active) {
+			break
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	return "slow: " + s.Text
+}
+
+func TestParallelToolCalls(t *testing.T) {
+	calls := []types.CompletionChunk{{ToolCalls: []types.ToolCallDelta{
+		{Index: 0, ID: "a", Name: "slow", Args: `{"text":"a"}`},
+		{Index: 1, ID: "b", Name: "slow", Args: `{"text":"b"}`},
+		{Index: 2, ID: "c", Name: "write", Args: `{"text":"c"}`},
+		{Index: 3, ID: "d", Name: "slow", Args: `{"text":"d"}`},
+	}}}
+
+	tests := []struct {
+		name     string
+		opts     []RunOption
+		wantPeak int32
+	}{
+		{"unbounded", nil, 2},
+		{"sequential", []RunOption{WithToolConcurrency(1)}, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			slowPeak.Store(0)
+			provider := &fakeProvider{turns: [][]types.CompletionChunk{calls, {{Content: "done"}}}}
+			agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+			agent.WithTools(
+				tools.NewTool("slow", "Slow tool.", tools.ToolArgs[struct{}](slowArgs{})),
+				tools.Tool[struct{}]{Name: "write", Description: "Side effects.", Args: slowArgs{}, Sequential: true},
+			)
+
+			resp, err := Run(context.Background(), *agent, Input{OfString: "go"}, &struct{}{}, test.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			order := []string{}
+			for _, msg := range resp.FinalConversation() {
+				if msg.Role == types.Tool {
+					order = append(order, msg.ID)
+				}
+			}
+			if strings.Join(order, "") != "abcd" {
+				t.Fatalf("tool results out of order: %v", order)
+			}
+			if peak := slowPeak.Load(); peak != test.wantPeak {
+				t.Fatalf("expected peak concurrency %d, got %d", test.wantPeak, peak)
+			}
+		})
+	}
+}
+
+func TestParallelToolCallsShareNoMemory(t *testing.T) {
+	calls := []types.CompletionChunk{{ToolCalls: []types.ToolCallDelta{
+		{Index: 0, ID: "a", Name: "slow", Args: `{"text":"a"}`},
+		{Index: 1, ID: "b", Name: "slow", Args: `{"text":"b"}`},
+		{Index: 2, ID: "c", Name: "slow", Args: `{"text":"c"}`},
+	}}}
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{calls, {{Content: "done"}}}}
+	model := types.ModelConfig{Model: "fake", Provider: provider}
+	agent := types.NewAgent[struct{}]("tester", model).
+		WithHandoffs([]types.Handoff[struct{}]{{Agent: types.NewAgent[struct{}]("expert", model)}})
+	// spare capacity in the agent's tools must not be written to while the
+	// calls look their tools up
+	agent.Tools = append(make([]tools.Tool[struct{}], 0, 8), tools.NewTool("slow", "Slow tool.", tools.ToolArgs[struct{}](slowArgs{})))
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "go"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := resp.Status(); status != StatusCompleted {
+		t.Fatalf("expected completed run, got %v: %v", status, resp.Err())
+	}
+	if results := len(resp.FinalConversation()); results != 6 {
+		t.Fatalf("expected every call to be answered, got %d messages", results)
+	}
+	if len(agent.Tools) != 1 || len(agent.AllTools()) != 2 || len(agent.Tools[:cap(agent.Tools)][1].Name) != 0 {
+		t.Fatalf("expected the agent's tools to be left alone, got %+v", agent.Tools[:2])
+	}
+}
+
+// flakyProvider fails with the scripted errors before delegating to next.
+type flakyProvider struct {
+	errs  []error
+	next  types.Provider
+	calls int
+}
+
+func (p *flakyProvider) Stream(ctx context.Context, req types.CompletionRequest) (types.CompletionStream, error) {
+	p.calls++
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		return nil, err
+	}
+	return p.next.Stream(ctx, req)
+}
+
+func TestRunRetriesTransientErrors(t *testing.T) {
+	provider := &flakyProvider{
+		errs: []error{&providers.HTTPError{Provider: "fake", StatusCode: 503, Body: "unavailable"}},
+		next: &fakeProvider{turns: [][]types.CompletionChunk{{{Content: "recovered"}}}},
+	}
+	model := types.ModelConfig{Model: "fake", Provider: provider, Retry: types.RetryPolicy{InitialBackoff: time.Millisecond}}
+	agent := types.NewAgent[struct{}]("tester", model)
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	retries := 0
+	for event := range resp.Stream() {
+		if retry, ok := event.Retry(); ok {
+			retries++
+			if retry.Attempt != 1 || retry.MaxAttempts != 3 {
+				t.Fatalf("unexpected retry event %+v", retry)
+			}
+		}
+	}
+	if retries != 1 || provider.calls != 2 {
+		t.Fatalf("expected one retry, got %d retries and %d calls", retries, provider.calls)
+	}
+	if resp.Status() != StatusCompleted || resp.Response().Content != "recovered" {
+		t.Fatalf("expected recovered answer, got %v %q", resp.Status(), resp.Response().Content)
+	}
+}
+
+func TestRunFailsOnPermanentErrors(t *testing.T) {
+	badRequest := &providers.HTTPError{Provider: "fake", StatusCode: 400, Body: "bad request"}
+	provider := &flakyProvider{errs: []error{badRequest}, next: &fakeProvider{}}
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var streamed error
+	for event := range resp.Stream() {
+		if err, ok := event.Error(); ok {
+			streamed = err
+		}
+	}
+	if provider.calls != 1 {
+		t.Fatalf("expected no retries, got %d calls", provider.calls)
+	}
+	if resp.Status() != StatusFailed || !errors.Is(resp.Err(), badRequest) || streamed != resp.Err() {
+		t.Fatalf("expected failed run with the request error, got %v %v", resp.Status(), resp.Err())
+	}
+}
+
+func TestRunFailsOnEmptyResponse(t *testing.T) {
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: &fakeProvider{}})
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !errors.Is(resp.Err(), ErrEmptyResponse) {
+		t.Fatalf("expected ErrEmptyResponse, got %v", resp.Err())
+	}
+}
+
+type weather struct {
+	City        string `json:"city"`
+	Temperature int    `json:"temperature"`
+}
+
+func (w *weather) Validate() error {
+	if w.Temperature < -100 {
+		return errors.New("temperature out of range")
+	}
+	return nil
+}
+
+// formatProvider is a fakeProvider that supports native response formats.
+type formatProvider struct{ fakeProvider }
+
+func (p *formatProvider) SupportsResponseFormat() bool { return true }
+
+func TestStructuredOutputViaResponseFormat(t *testing.T) {
+	provider := &formatProvider{fakeProvider{turns: [][]types.CompletionChunk{
+		{{Content: `{"city":"Oslo","temperature":-500}`}},
+		{{Content: "```json\n{\"city\":\"Oslo\",\"temperature\":4}\n```"}},
+	}}}
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+	agent.WithOutputType(weather{})
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "weather?"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output, err := FinalOutput[weather](resp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != (weather{City: "Oslo", Temperature: 4}) {
+		t.Fatalf("unexpected output %+v", output)
+	}
+
+	first := provider.requests[0]
+	if first.ResponseFormat == nil || first.ResponseFormat.Name != "weather" || len(first.Tools) != 0 {
+		t.Fatalf("expected a response format instead of tools, got %+v", first)
+	}
+	retry := provider.requests[1].Messages
+	if last := retry[len(retry)-1]; last.Role != types.User || !strings.Contains(last.Content, "temperature out of range") {
+		t.Fatalf("expected the validation error to be sent back, got %+v", last)
+	}
+}
+
+func TestStructuredOutputViaTool(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "final_output", Args: `{"city":"Oslo"}`}}}},
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_2", Name: "final_output", Args: `{"city":"Oslo","temperature":4}`}}}},
+	}}
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+	agent.WithOutputType(weather{})
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "weather?"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output, err := FinalOutput[weather](resp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Temperature != 4 {
+		t.Fatalf("unexpected output %+v", output)
+	}
+
+	if tools := provider.requests[0].Tools; len(tools) != 1 || tools[0].Name != "final_output" {
+		t.Fatalf("expected the final_output tool, got %+v", tools)
+	}
+	retry := provider.requests[1].Messages
+	if last := retry[len(retry)-1]; last.Role != types.Tool || !strings.Contains(last.Content, `missing required field "temperature"`) {
+		t.Fatalf("expected the validation error as tool result, got %+v", last)
+	}
+	if answer := resp.Response(); len(answer.ToolCalls) != 0 || answer.Content != `{"city":"Oslo","temperature":4}` {
+		t.Fatalf("expected the submission recorded as the answer, got %+v", answer)
+	}
+}
+
+// slowProvider waits before answering so guardrails can finish first.
+type slowProvider struct {
+	fakeProvider
+	delay time.Duration
+}
+
+func (p *slowProvider) Stream(ctx context.Context, req types.CompletionRequest) (types.CompletionStream, error) {
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return p.fakeProvider.Stream(ctx, req)
+}
+
+func TestInputGuardrailTrips(t *testing.T) {
+	provider := &slowProvider{
+		fakeProvider: fakeProvider{turns: [][]types.CompletionChunk{{{Content: "sure"}}}},
+		delay:        time.Second,
+	}
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+	agent.WithInputGuardrails(
+		types.NewGuardrail("no_secrets", "asks for secrets", func(_ *struct{}, text string) bool {
+			return strings.Contains(text, "password")
+		}),
+	)
+
+	start := time.Now()
+	resp, err := Run(context.Background(), *agent, Input{OfString: "what is the admin password?"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var tripped *GuardrailTripped
+	for event := range resp.Stream() {
+		if g, ok := event.GuardrailTripped(); ok {
+			tripped = g
+		}
+	}
+	if tripped == nil || tripped.Guardrail != "no_secrets" || tripped.Stage != GuardrailInput || tripped.Reason != "asks for secrets" {
+		t.Fatalf("expected a tripped input guardrail event, got %+v", tripped)
+	}
+	if !errors.Is(resp.Err(), ErrGuardrailTripped) || resp.Status() != StatusFailed {
+		t.Fatalf("expected the run to fail with the guardrail, got %v %v", resp.Status(), resp.Err())
+	}
+	if time.Since(start) >= provider.delay {
+		t.Fatalf("expected the model call to be aborted")
+	}
+	if len(resp.FinalConversation()) != 1 {
+		t.Fatalf("expected no assistant message, got %+v", resp.FinalConversation())
+	}
+}
+
+func TestOutputGuardrailTrips(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{{{Content: "the password is hunter2"}}}}
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+	agent.WithInputGuardrails(types.NewGuardrail("never", "", func(*struct{}, string) bool { return false }))
+	agent.WithOutputGuardrails(types.Guardrail[struct{}]{
+		Name: "leak",
+		Check: func(_ context.Context, _ *struct{}, text string) (types.GuardrailResult, error) {
+			return types.GuardrailResult{Tripped: strings.Contains(text, "hunter2"), Reason: "leaks a password"}, nil
+		},
+	})
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hi"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var tripped *GuardrailTripped
+	if !errors.As(resp.Err(), &tripped) || tripped.Stage != GuardrailOutput || tripped.Guardrail != "leak" {
+		t.Fatalf("expected a tripped output guardrail, got %v", resp.Err())
+	}
+}
+
+func TestRequestTimeoutRetries(t *testing.T) {
+	provider := &slowProvider{delay: time.Second}
+	model := types.ModelConfig{
+		Model:          "fake",
+		Provider:       provider,
+		RequestTimeout: 20 * time.Millisecond,
+		Retry:          types.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+	}
+	agent := types.NewAgent[struct{}]("tester", model)
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	retries := 0
+	for event := range resp.Stream() {
+		if _, ok := event.Retry(); ok {
+			retries++
+		}
+	}
+	if retries != 1 || !errors.Is(resp.Err(), ErrRequestTimeout) {
+		t.Fatalf("expected one retry and a timeout error, got %d retries and %v", retries, resp.Err())
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{
+			{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "echo", Args: `{"text":"hi"}`}}},
+			{Usage: &types.Usage{InputTokens: 100, OutputTokens: 20, TotalTokens: 120}},
+		},
+		{{Content: "done"}, {Usage: &types.Usage{InputTokens: 150, OutputTokens: 10}}},
+	}}
+	model := types.ModelConfig{Model: "fake", Provider: provider, Pricing: &types.ModelPrice{InputPerMillion: 1, OutputPerMillion: 10}}
+	agent := types.NewAgent[struct{}]("tester", model)
+	agent.WithTools(tools.NewTool("echo", "Echo text back.", tools.ToolArgs[struct{}](echoArgs{})))
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var events []UsageEvent
+	for event := range resp.Stream() {
+		if usage, ok := event.Usage(); ok {
+			events = append(events, *usage)
+		}
+	}
+	if len(events) != 2 || events[1].Usage.TotalTokens != 160 || events[0].Agent != "tester" {
+		t.Fatalf("unexpected usage events %+v", events)
+	}
+
+	want := types.Usage{InputTokens: 250, OutputTokens: 30, TotalTokens: 280}
+	if resp.Usage() != want || resp.UsageByAgent()["tester"] != want {
+		t.Fatalf("unexpected usage %+v / %+v", resp.Usage(), resp.UsageByAgent())
+	}
+	if cost := resp.Cost(); cost < 0.000549 || cost > 0.000551 {
+		t.Fatalf("unexpected cost %v", cost)
+	}
+}
+
+// memorySession is a minimal Session for tests.
+type memorySession struct{ messages []types.Message }
+
+func (s *memorySession) Load(context.Context) ([]types.Message, error) { return s.messages, nil }
+func (s *memorySession) Append(_ context.Context, messages ...types.Message) error {
+	s.messages = append(s.messages, messages...)
+	return nil
+}
+func (s *memorySession) Clear(context.Context) error { s.messages = nil; return nil }
+
+func TestRunWithSession(t *testing.T) {
+	session := &memorySession{}
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{Content: "hi Ana"}},
+		{{Content: "your name is Ana"}},
+	}}
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+
+	for _, prompt := range []string{"I am Ana", "what is my name?"} {
+		resp, err := Run(context.Background(), *agent, Input{OfString: prompt}, &struct{}{}, WithSession(session))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Status() != StatusCompleted {
+			t.Fatalf("unexpected status %v: %v", resp.Status(), resp.Err())
+		}
+	}
+
+	// system prompt plus both turns
+	if sent := provider.requests[1].Messages; len(sent) != 4 || sent[1].Content != "I am Ana" {
+		t.Fatalf("expected the history to be prepended, got %+v", sent)
+	}
+	if len(session.messages) != 4 || session.messages[3].Content != "your name is Ana" {
+		t.Fatalf("expected both turns saved once, got %+v", session.messages)
+	}
+}
+
+func TestRunCompactsHistory(t *testing.T) {
+	session := &memorySession{messages: []types.Message{
+		types.NewUserMessage("first question"),
+		types.NewAssistantMessage("first answer", "tester", nil),
+		types.NewUserMessage("second question"),
+		types.NewAssistantMessage("second answer", "tester", nil),
+	}}
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{{{Content: "third answer"}}}}
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider}).
+		WithHistory(history.LastTurns(1))
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "third question"}, &struct{}{}, WithSession(session))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var compaction *CompactionEvent
+	for event := range resp.Stream() {
+		if c, ok := event.Compaction(); ok {
+			compaction = c
+		}
+	}
+
+	if compaction == nil || compaction.MessagesBefore != 5 || compaction.MessagesAfter != 1 {
+		t.Fatalf("expected a compaction from 5 to 1 messages, got %+v", compaction)
+	}
+	if compaction.TokensAfter >= compaction.TokensBefore {
+		t.Fatalf("expected fewer tokens after compaction, got %+v", compaction)
+	}
+	if sent := provider.requests[0].Messages; len(sent) != 2 || sent[1].Content != "third question" {
+		t.Fatalf("expected only the last turn to be sent, got %+v", sent)
+	}
+	if len(resp.FinalConversation()) != 6 {
+		t.Fatalf("expected the full conversation to be kept, got %d messages", len(resp.FinalConversation()))
+	}
+}
+
+// spanRecorder keeps the spans that end.
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []tracing.Span
+}
+
+func (r *spanRecorder) OnStart(tracing.Span) {}
+func (r *spanRecorder) OnEnd(span tracing.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+func (r *spanRecorder) Shutdown(context.Context) error { return nil }
+
+func TestRunTracing(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "echo", Args: `{"text":"hi"}`}}}},
+		{{Content: "done", Usage: &types.Usage{InputTokens: 10, OutputTokens: 2}}},
+	}}
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider}).
+		WithInputGuardrails(types.NewGuardrail("never", "", func(*struct{}, string) bool { return false }))
+	agent.WithTools(tools.NewTool("echo", "Echo text back.", tools.ToolArgs[struct{}](echoArgs{})))
+
+	recorder := &spanRecorder{}
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{},
+		WithTracer(tracing.NewTracer(recorder)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := resp.Err(); err != nil {
+		t.Fatalf("unexpected run error: %v", err)
+	}
+
+	kinds := map[tracing.SpanKind][]tracing.Span{}
+	for _, span := range recorder.spans {
+		kinds[span.Kind] = append(kinds[span.Kind], span)
+	}
+	if len(kinds[tracing.KindRun]) != 1 || len(kinds[tracing.KindGeneration]) != 2 ||
+		len(kinds[tracing.KindTool]) != 1 || len(kinds[tracing.KindGuardrail]) != 1 {
+		t.Fatalf("unexpected spans %+v", kinds)
+	}
+	run := kinds[tracing.KindRun][0]
+	for _, span := range recorder.spans {
+		if span.TraceID != run.TraceID || (span.Kind != tracing.KindRun && span.ParentID != run.SpanID) {
+			t.Fatalf("expected %s span %q nested in the run", span.Kind, span.Name)
+		}
+	}
+	if run.Input != "hello" || run.Output != "done" || run.Usage.InputTokens != 10 {
+		t.Fatalf("unexpected run span %+v", run)
+	}
+	if tool := kinds[tracing.KindTool][0]; tool.Output != "echo: hi" {
+		t.Fatalf("unexpected tool span %+v", tool)
+	}
+}
+
+func TestRunLifecycleEvents(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "echo", Args: `{"text":"hi"}`}}}},
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_2", Name: "transfer_to_expert", Args: `{"prompt":"help"}`}}}},
+		{{Content: "done"}},
+	}}
+	model := types.ModelConfig{Model: "fake", Provider: provider}
+	expert := types.NewAgent[struct{}]("expert", model)
+	agent := types.NewAgent[struct{}]("tester", model).
+		WithHandoffs([]types.Handoff[struct{}]{{Agent: expert}})
+	agent.WithTools(tools.NewTool("echo", "Echo text back.", tools.ToolArgs[struct{}](echoArgs{})))
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var events []AgentEvent
+	for event := range resp.Stream() {
+		if event.RunID != resp.RunID() {
+			t.Fatalf("expected run ID %q, got %q", resp.RunID(), event.RunID)
+		}
+		events = append(events, event)
+	}
+
+	if _, ok := events[0].RunStarted(); !ok || events[0].Turn != 0 || events[0].Agent != "tester" {
+		t.Fatalf("expected the run to open with RunStarted, got %+v", events[0])
+	}
+	completed, ok := events[len(events)-1].RunCompleted()
+	if !ok || completed.Status != StatusCompleted || events[len(events)-1].Agent != "expert" {
+		t.Fatalf("expected the run to close with RunCompleted, got %+v", events[len(events)-1])
+	}
+
+	var turns, requests int
+	var started *ToolCallStartedEvent
+	var finished *ToolCallFinishedEvent
+	var switched *AgentSwitchedEvent
+	for _, event := range events {
+		if _, ok := event.TurnStarted(); ok {
+			turns++
+			if event.Turn != turns {
+				t.Fatalf("expected turn %d, got %d", turns, event.Turn)
+			}
+		}
+		if _, ok := event.LLMRequestSent(); ok {
+			requests++
+		}
+		if e, ok := event.ToolCallStarted(); ok && e.Name == "echo" {
+			started = e
+		}
+		if e, ok := event.ToolCallFinished(); ok && e.Name == "echo" {
+			finished = e
+		}
+		if e, ok := event.AgentSwitched(); ok {
+			switched = e
+		}
+	}
+	if turns != 3 || requests != 3 {
+		t.Fatalf("expected 3 turns and requests, got %d and %d", turns, requests)
+	}
+	if started == nil || started.Args["text"] != "hi" {
+		t.Fatalf("expected parsed tool arguments, got %+v", started)
+	}
+	if finished == nil || finished.Result != "echo: hi" || finished.Err != nil {
+		t.Fatalf("unexpected tool call result %+v", finished)
+	}
+	if switched == nil || switched.FromAgent != "tester" || switched.ToAgent != "expert" {
+		t.Fatalf("unexpected agent switch %+v", switched)
+	}
+}
+
+func TestRunStreamsToolCallDeltas(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{
+			{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "echo", Args: `{"text": "hel`}}},
+			{ToolCalls: []types.ToolCallDelta{{Index: 0, Args: `lo"}`}}},
+		},
+		{{Content: "done"}},
+	}}
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+	agent.WithTools(tools.NewTool("echo", "Echo text back.", tools.ToolArgs[struct{}](echoArgs{})))
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var deltas []ToolCallDeltaEvent
+	for event := range resp.Stream() {
+		if delta, ok := event.ToolCallDelta(); ok {
+			deltas = append(deltas, *delta)
+		}
+	}
+
+	if len(deltas) != 2 {
+		t.Fatalf("expected one event per fragment, got %+v", deltas)
+	}
+	last := deltas[1]
+	if last.ToolCallID != "call_1" || last.Name != "echo" || last.ArgsDelta != `lo"}` || last.Args != `{"text": "hello"}` {
+		t.Fatalf("unexpected fragment %+v", last)
+	}
+	if preview := deltas[0].PartialArgs(); preview["text"] != "hel" {
+		t.Fatalf("expected a preview of the arguments, got %v", preview)
+	}
+}
+
+func TestRunSeparatesInlineReasoning(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{Content: "<think>\nadd"}, {Content: " them</thi"}, {Content: "nk>\n\n4"}},
+	}}
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "2+2?"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var reasoning, content string
+	for event := range resp.Stream() {
+		if token, ok := event.ReasoningToken(); ok {
+			reasoning += token
+		}
+		if token, ok := event.Token(); ok {
+			content += token
+		}
+	}
+
+	if reasoning != "add them" || content != "4" {
+		t.Fatalf("expected reasoning and answer apart, got %q and %q", reasoning, content)
+	}
+	if answer := resp.Response(); answer.Content != "4" || answer.Reasoning != "add them" {
+		t.Fatalf("expected reasoning stored apart from the answer, got %+v", answer)
+	}
+}
+
+func TestRunToolApproval(t *testing.T) {
+	tests := []struct {
+		name    string
+		resolve func(resp *AgentResponse, id string) error
+		want    string
+	}{
+		{"approve", func(resp *AgentResponse, id string) error { return resp.Approve(id) }, "echo: hi"},
+		{"edit", func(resp *AgentResponse, id string) error { return resp.ApproveWithArgs(id, `{"text":"bye"}`) }, "echo: bye"},
+		{"reject", func(resp *AgentResponse, id string) error { return resp.Reject(id, "not now") }, "tool call rejected by the user: not now"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{turns: [][]types.CompletionChunk{
+				{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "echo", Args: `{"text":"hi"}`}}}},
+				{{Content: "done"}},
+			}}
+			echo := tools.NewTool("echo", "Echo text back.", tools.ToolArgs[struct{}](echoArgs{}))
+			echo.NeedsApproval = true
+			agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+			agent.WithTools(echo)
+
+			resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for event := range resp.Stream() {
+				request, ok := event.ApprovalRequested()
+				if !ok {
+					continue
+				}
+				if request.Name != "echo" || request.Args["text"] != "hi" {
+					t.Fatalf("unexpected approval request %+v", request)
+				}
+				if pending := resp.PendingApprovals(); len(pending) != 1 || pending[0].ToolCallID != "call_1" {
+					t.Fatalf("expected call_1 to be pending, got %+v", pending)
+				}
+				if err := tt.resolve(resp, request.ToolCallID); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if err := resp.Approve(request.ToolCallID); !errors.Is(err, ErrNoPendingApproval) {
+					t.Fatalf("expected ErrNoPendingApproval, got %v", err)
+				}
+			}
+
+			if status := resp.Status(); status != StatusCompleted {
+				t.Fatalf("expected completed run, got %v: %v", status, resp.Err())
+			}
+			result := resp.FinalConversation()[2]
+			if result.Role != types.Tool || result.Content != tt.want {
+				t.Fatalf("expected tool result %q, got %+v", tt.want, result)
+			}
+		})
+	}
+}
+
+func TestRunAnswersToolCallsThatCannotRun(t *testing.T) {
+	tests := []struct {
+		name  string
+		call  string
+		hooks *types.LifecycleHooks[struct{}]
+		want  string
+	}{
+		{"unknown tool", "missing", nil, `tool "missing" not found`},
+		{"vetoed by hook", "echo", &types.LifecycleHooks[struct{}]{
+			BeforeToolCall: func(*struct{}, string, string) error { return errors.New("not allowed") },
+		}, "tool call blocked: not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{turns: [][]types.CompletionChunk{
+				{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: tt.call, Args: `{"text":"hi"}`}}}},
+				{{Content: "done"}},
+			}}
+			agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+			agent.WithTools(tools.NewTool("echo", "Echo text back.", tools.ToolArgs[struct{}](echoArgs{})))
+			agent.Hooks = tt.hooks
+
+			resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if status := resp.Status(); status != StatusCompleted {
+				t.Fatalf("expected completed run, got %v: %v", status, resp.Err())
+			}
+			result := resp.FinalConversation()[2]
+			if result.Role != types.Tool || result.ID != "call_1" || result.Content != tt.want {
+				t.Fatalf("expected the call to be answered with %q, got %+v", tt.want, result)
+			}
+			if messages := provider.requests[1].Messages; messages[len(messages)-1].ID != "call_1" {
+				t.Fatalf("expected the answer to be sent to the model, got %+v", messages)
+			}
+		})
+	}
+}
+
+func TestRunApprovalPolicy(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "echo", Args: `{"text":"hi"}`}}}},
+		{{Content: "done"}},
+	}}
+	echo := tools.NewTool("echo", "Echo text back.", tools.ToolArgs[struct{}](echoArgs{}))
+	echo.NeedsApproval = true
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider}).
+		WithApprovalPolicy(types.RequireApprovalForNone[struct{}])
+	agent.WithTools(echo)
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result := resp.FinalConversation()[2]; result.Content != "echo: hi" {
+		t.Fatalf("expected the call to run unattended, got %+v", result)
+	}
+}
+
+func TestRunResumesFromState(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "echo", Args: `{"text":"hi"}`}}}},
+		{{Content: "done"}},
+	}}
+	echo := tools.NewTool("echo", "Echo text back.", tools.ToolArgs[struct{}](echoArgs{}))
+	echo.NeedsApproval = true
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+	agent.WithTools(echo)
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// stop the run while it waits for approval
+	for event := range resp.Stream() {
+		if _, ok := event.ApprovalRequested(); ok {
+			resp.Stop()
+		}
+	}
+	if status := resp.Status(); status != StatusCancelled {
+		t.Fatalf("expected cancelled run, got %v", status)
+	}
+
+	saved, err := json.Marshal(resp.State())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var state RunState
+	if err := json.Unmarshal(saved, &state); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.RunID != resp.RunID() || state.Agent != "tester" || state.Turns != 1 {
+		t.Fatalf("unexpected state %+v", state)
+	}
+	if len(state.PendingToolCalls) != 1 || state.PendingToolCalls[0].ID != "call_1" {
+		t.Fatalf("expected call_1 to be pending, got %+v", state.PendingToolCalls)
+	}
+	if len(state.Messages) != 2 || state.Messages[1].Role != types.Assistant {
+		t.Fatalf("expected the cancelled result to be dropped, got %+v", state.Messages)
+	}
+
+	if _, err := Resume(context.Background(), types.NewAgentRegistry[struct{}](), state, &struct{}{}); !errors.Is(err, ErrUnknownAgent) {
+		t.Fatalf("expected ErrUnknownAgent, got %v", err)
+	}
+	resumed, err := Resume(context.Background(), types.NewAgentRegistry(agent), state, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for event := range resumed.Stream() {
+		if request, ok := event.ApprovalRequested(); ok {
+			resumed.Approve(request.ToolCallID)
+		}
+	}
+	if status := resumed.Status(); status != StatusCompleted {
+		t.Fatalf("expected completed run, got %v: %v", status, resumed.Err())
+	}
+	if resumed.RunID() != state.RunID {
+		t.Fatalf("expected the run ID to be kept, got %q", resumed.RunID())
+	}
+	messages := resumed.FinalConversation()
+	if len(messages) != 4 || messages[2].Content != "echo: hi" || messages[3].Content != "done" {
+		t.Fatalf("unexpected conversation %+v", messages)
+	}
+	if final := resumed.State(); final.Turns != 2 || final.ToolCalls != 1 || len(final.PendingToolCalls) != 0 {
+		t.Fatalf("unexpected final state %+v", final)
+	}
+}
+
+func TestResumeWithSession(t *testing.T) {
+	session := &memorySession{}
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "echo", Args: `{"text":"hi"}`}}}},
+		{{Content: "done"}},
+	}}
+	echo := tools.NewTool("echo", "Echo text back.", tools.ToolArgs[struct{}](echoArgs{}))
+	echo.NeedsApproval = true
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+	agent.WithTools(echo)
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{}, WithSession(session))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for event := range resp.Stream() {
+		if _, ok := event.ApprovalRequested(); ok {
+			resp.Stop()
+		}
+	}
+	resumed, err := Resume(context.Background(), types.NewAgentRegistry(agent), resp.State(), &struct{}{}, WithSession(session))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for event := range resumed.Stream() {
+		if request, ok := event.ApprovalRequested(); ok {
+			resumed.Approve(request.ToolCallID)
+		}
+	}
+	if status := resumed.Status(); status != StatusCompleted {
+		t.Fatalf("expected completed run, got %v: %v", status, resumed.Err())
+	}
+
+	results := map[string][]string{}
+	for _, msg := range session.messages {
+		if msg.Role == types.Tool {
+			results[msg.ID] = append(results[msg.ID], msg.Content)
+		}
+	}
+	if len(results) != 1 || !slices.Equal(results["call_1"], []string{"echo: hi"}) {
+		t.Fatalf("expected a single result per tool call in the session, got %v", results)
+	}
+	if len(session.messages) != 4 || session.messages[3].Content != "done" {
+		t.Fatalf("unexpected session %+v", session.messages)
+	}
+}
+
+func TestResumeWithRepeatedToolCallIDs(t *testing.T) {
+	session := &memorySession{}
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_0", Name: "echo", Args: `{"text":"first"}`}}}},
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_0", Name: "echo", Args: `{"text":"second"}`}}}},
+		{{Content: "done"}},
+	}}
+	echo := tools.NewTool("echo", "Echo text back.", tools.ToolArgs[struct{}](echoArgs{}))
+	echo.NeedsApproval = true
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider})
+	agent.WithTools(echo)
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{}, WithSession(session))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// approve the first call, then stop while the second waits
+	approved := false
+	for event := range resp.Stream() {
+		if request, ok := event.ApprovalRequested(); ok {
+			if approved {
+				resp.Stop()
+				continue
+			}
+			approved = true
+			resp.Approve(request.ToolCallID)
+		}
+	}
+	state := resp.State()
+	if len(state.Messages) != 4 || state.Messages[2].Content != "echo: first" {
+		t.Fatalf("expected the first turn's result to be kept, got %+v", state.Messages)
+	}
+
+	resumed, err := Resume(context.Background(), types.NewAgentRegistry(agent), state, &struct{}{}, WithSession(session))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for event := range resumed.Stream() {
+		if request, ok := event.ApprovalRequested(); ok {
+			resumed.Approve(request.ToolCallID)
+		}
+	}
+	if status := resumed.Status(); status != StatusCompleted {
+		t.Fatalf("expected completed run, got %v: %v", status, resumed.Err())
+	}
+	var results []string
+	for _, msg := range session.messages {
+		if msg.Role == types.Tool {
+			results = append(results, msg.Content)
+		}
+	}
+	if !slices.Equal(results, []string{"echo: first", "echo: second"}) {
+		t.Fatalf("expected one result per turn in the session, got %v", results)
+	}
+}
+
+func TestHandoffInputFilter(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "echo", Args: `{"text":"hi"}`}}}},
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_2", Name: "transfer_to_expert", Args: `{"prompt":"take over"}`}}}},
+		{{Content: "done"}},
+	}}
+	model := types.ModelConfig{Model: "fake", Provider: provider}
+	expert := types.NewAgent[struct{}]("expert", model)
+	other := types.NewAgent[struct{}]("other", model)
+	agent := types.NewAgent[struct{}]("tester", model).
+		WithHandoffs([]types.Handoff[struct{}]{
+			{Agent: other},
+			{Agent: expert, InputFilter: history.DropToolCalls(), PromptMode: types.PromptAsSystemNote},
+		})
+	agent.WithTools(tools.NewTool("echo", "Echo text back.", tools.ToolArgs[struct{}](echoArgs{})))
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := resp.Status(); status != StatusCompleted {
+		t.Fatalf("expected completed run, got %v: %v", status, resp.Err())
+	}
+
+	// the expert sees the user's words and the prompt, not the tool calls
+	seen := provider.requests[2].Messages[1:]
+	if len(seen) != 2 || seen[0].Content != "hello" || seen[1].Role != types.System || seen[1].Content != "take over" {
+		t.Fatalf("unexpected messages sent to the expert %+v", seen)
+	}
+	// the transcript keeps everything and gains no fabricated messages
+	if messages := resp.FinalConversation(); len(messages) != 6 || messages[5].Content != "done" {
+		t.Fatalf("unexpected transcript %+v", messages)
+	}
+	if state := resp.State(); state.Agent != "expert" || len(state.AgentView) != 2 || state.ViewFrom != 5 {
+		t.Fatalf("unexpected state %+v", state)
+	}
+}
+
+type ticket struct {
+	Escalated string
+}
+
+type escalation struct {
+	Reason string `json:"reason"`
+}
+
+func TestHandoffTypedInput(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "transfer_to_expert", Args: `{"reason": 42}`}}}},
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_2", Name: "transfer_to_expert", Args: `{"reason":"refund"}`}}}},
+		{{Content: "done"}},
+	}}
+	model := types.ModelConfig{Model: "fake", Provider: provider}
+	expert := types.NewAgent[ticket]("expert", model)
+	agent := types.NewAgent[ticket]("tester", model).
+		WithHandoffs([]types.Handoff[ticket]{{
+			Agent:     expert,
+			InputType: escalation{},
+			OnHandoff: types.OnHandoffInput(func(ctx *ticket, input escalation) error {
+				ctx.Escalated = input.Reason
+				return nil
+			}),
+		}})
+
+	ctx := &ticket{}
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := resp.Status(); status != StatusCompleted {
+		t.Fatalf("expected completed run, got %v: %v", status, resp.Err())
+	}
+
+	messages := resp.FinalConversation()
+	if refused := messages[2]; refused.Role != types.Tool || !strings.Contains(refused.Content, "invalid handoff arguments") {
+		t.Fatalf("expected malformed arguments to be reported, got %+v", refused)
+	}
+	if provider.requests[1].Tools[0].Name != "transfer_to_expert" || provider.requests[2].Tools[0].Name != "return_to_tester" {
+		t.Fatalf("expected the refused handoff to keep the agent")
+	}
+	if ctx.Escalated != "refund" {
+		t.Fatalf("expected OnHandoff to record the input, got %+v", ctx)
+	}
+	seen := provider.requests[2].Messages
+	if last := seen[len(seen)-1]; last.Role != types.User || last.Content != `{"reason":"refund"}` {
+		t.Fatalf("expected the input to be passed as the prompt, got %+v", last)
+	}
+}
+
+func TestHandoffReturnsToCaller(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "transfer_to_specialist", Args: `{"prompt":"fix it"}`}}}},
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_2", Name: "return_to_triage", Args: `{"summary":"fixed"}`}}}},
+		{{Content: "done"}},
+	}}
+	model := types.ModelConfig{Model: "fake", Provider: provider}
+	specialist := types.NewAgent[struct{}]("specialist", model)
+	triage := types.NewAgent[struct{}]("triage", model).
+		WithHandoffs([]types.Handoff[struct{}]{{Agent: specialist}})
+
+	resp, err := Run(context.Background(), *triage, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := resp.Status(); status != StatusCompleted {
+		t.Fatalf("expected completed run, got %v: %v", status, resp.Err())
+	}
+	if chain := resp.HandoffChain(); !slices.Equal(chain, []string{"triage", "specialist", "triage"}) {
+		t.Fatalf("unexpected handoff chain %v", chain)
+	}
+
+	// the triage agent sees the conversation without the specialist's
+	// prompt, and has no caller to return to
+	request := provider.requests[2]
+	last := request.Messages[len(request.Messages)-1]
+	if last.Role != types.Tool || last.Content != "Returning to triage agent" {
+		t.Fatalf("unexpected last message %+v", last)
+	}
+	for _, msg := range request.Messages {
+		if msg.Content == "fix it" {
+			t.Fatalf("expected the prompt to stay with the specialist")
+		}
+	}
+	if len(request.Tools) != 1 || request.Tools[0].Name != "transfer_to_specialist" {
+		t.Fatalf("unexpected tools %+v", request.Tools)
+	}
+}
+
+func TestHandoffCycles(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []RunOption
+		status RunStatus
+		err    error
+	}{
+		{"refuse", nil, StatusCompleted, nil},
+		{"fail", []RunOption{WithHandoffCyclePolicy(HandoffCycleFail)}, StatusFailed, ErrHandoffCycle},
+		{"depth", []RunOption{WithMaxHandoffDepth(1), WithHandoffCyclePolicy(HandoffCycleAllow)}, StatusFailed, ErrMaxHandoffDepth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{turns: [][]types.CompletionChunk{
+				{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "transfer_to_specialist", Args: `{"prompt":"fix it"}`}}}},
+				{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_2", Name: "transfer_to_triage", Args: `{"prompt":"back to you"}`}}}},
+				{{Content: "done"}},
+			}}
+			model := types.ModelConfig{Model: "fake", Provider: provider}
+			specialist := types.NewAgent[struct{}]("specialist", model)
+			triage := types.NewAgent[struct{}]("triage", model).
+				WithHandoffs([]types.Handoff[struct{}]{{Agent: specialist}})
+			specialist.WithHandoffs([]types.Handoff[struct{}]{{Agent: triage}})
+
+			resp, err := Run(context.Background(), *triage, Input{OfString: "hello"}, &struct{}{}, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if status := resp.Status(); status != tt.status || !errors.Is(resp.Err(), tt.err) {
+				t.Fatalf("expected %v with %v, got %v with %v", tt.status, tt.err, status, resp.Err())
+			}
+			if chain := resp.HandoffChain(); !slices.Equal(chain, []string{"triage", "specialist"}) {
+				t.Fatalf("unexpected handoff chain %v", chain)
+			}
+			refused := resp.FinalConversation()[4]
+			if refused.Role != types.Tool || refused.ID != "call_2" {
+				t.Fatalf("expected the handoff to be answered, got %+v", refused)
+			}
+			if tt.name == "refuse" && !strings.Contains(refused.Content, "use return_to_triage instead") {
+				t.Fatalf("expected the model to be pointed to the return tool, got %q", refused.Content)
+			}
+		})
+	}
+}
+
+func TestHandoffPingPong(t *testing.T) {
+	transfer := []types.CompletionChunk{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "transfer_to_specialist", Args: `{"prompt":"fix it"}`}}}}
+	back := []types.CompletionChunk{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_2", Name: "return_to_triage", Args: `{"summary":"over to you"}`}}}}
+	tests := []struct {
+		name   string
+		opts   []RunOption
+		status RunStatus
+		err    error
+		chain  []string
+	}{
+		{"refuse", nil, StatusCompleted, nil, []string{"triage", "specialist", "triage"}},
+		{"fail", []RunOption{WithHandoffCyclePolicy(HandoffCycleFail)}, StatusFailed, ErrHandoffCycle, []string{"triage", "specialist", "triage"}},
+		{"allow", []RunOption{WithHandoffCyclePolicy(HandoffCycleAllow)}, StatusCompleted, nil, []string{"triage", "specialist", "triage", "specialist"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{turns: [][]types.CompletionChunk{transfer, back, transfer, {{Content: "done"}}}}
+			model := types.ModelConfig{Model: "fake", Provider: provider}
+			specialist := types.NewAgent[struct{}]("specialist", model)
+			triage := types.NewAgent[struct{}]("triage", model).
+				WithHandoffs([]types.Handoff[struct{}]{{Agent: specialist}})
+
+			resp, err := Run(context.Background(), *triage, Input{OfString: "hello"}, &struct{}{}, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if status := resp.Status(); status != tt.status || !errors.Is(resp.Err(), tt.err) {
+				t.Fatalf("expected %v with %v, got %v with %v", tt.status, tt.err, status, resp.Err())
+			}
+			if chain := resp.HandoffChain(); !slices.Equal(chain, tt.chain) {
+				t.Fatalf("unexpected handoff chain %v", chain)
+			}
+			if tt.name == "refuse" {
+				refused := resp.FinalConversation()[6]
+				if refused.Role != types.Tool || !strings.Contains(refused.Content, "cannot transfer to specialist again") {
+					t.Fatalf("expected the repeated transfer to be refused, got %+v", refused)
+				}
+			}
+		})
+	}
+}
+
+type notes struct{ taken []string }
+
+type noteArgs struct {
+	Text string `json:"text"`
+}
+
+func (n noteArgs) Run(ctx *notes) any {
+	ctx.taken = append(ctx.taken, n.Text)
+	return "noted"
+}
+
+func TestAgentTool(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "research", Args: `{"prompt":"look it up"}`}}}},
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_2", Name: "note", Args: `{"text":"found"}`}}}},
+		{{Content: "found it"}},
+		{{Content: "done"}},
+	}}
+	model := types.ModelConfig{Model: "fake", Provider: provider}
+	note := tools.NewTool("note", "Take a note.", tools.ToolArgs[notes](noteArgs{}))
+	note.NeedsApproval = true
+	researcher := types.NewAgent[notes]("researcher", model)
+	researcher.WithTools(note)
+	agent := types.NewAgent[notes]("lead", model)
+	agent.WithTools(AgentTool(*researcher, "research", "Research a question.", WithAgentToolOutput(ExtractLastMessages(1))))
+
+	ctx := &notes{}
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var nested string
+	for event := range resp.Stream() {
+		if event.ParentToolCallID == "" {
+			continue
+		}
+		if event.ParentToolCallID != "call_1" || event.Agent != "researcher" || event.RunID == resp.RunID() {
+			t.Fatalf("unexpected nested event %+v", event)
+		}
+		if token, ok := event.Token(); ok {
+			nested += token
+		}
+		// the nested call is resolved through the outer run
+		if request, ok := event.ApprovalRequested(); ok {
+			if err := resp.Approve(request.ToolCallID); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+
+	if status := resp.Status(); status != StatusCompleted {
+		t.Fatalf("expected completed run, got %v: %v", status, resp.Err())
+	}
+	if nested != "found it" {
+		t.Fatalf("expected the nested answer to be forwarded, got %q", nested)
+	}
+	if !slices.Equal(ctx.taken, []string{"found"}) {
+		t.Fatalf("expected the nested run to share the context, got %v", ctx.taken)
+	}
+	result := resp.FinalConversation()[2]
+	var last []types.Message
+	if err := json.Unmarshal([]byte(result.Content), &last); err != nil || result.Role != types.Tool {
+		t.Fatalf("unexpected tool result %+v", result)
+	}
+	if len(last) != 1 || last[0].Role != types.Assistant || last[0].Content != "found it" {
+		t.Fatalf("expected the nested run's last message, got %+v", last)
+	}
+}
+
+func TestAgentToolApprovalsWithSharedIDs(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{ToolCalls: []types.ToolCallDelta{
+			{Index: 0, ID: "call_1", Name: "note", Args: `{"text":"lead"}`},
+			{Index: 1, ID: "call_2", Name: "research", Args: `{"prompt":"look it up"}`},
+		}}},
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "note", Args: `{"text":"researcher"}`}}}},
+		{{Content: "found it"}},
+		{{Content: "done"}},
+	}}
+	model := types.ModelConfig{Model: "fake", Provider: provider}
+	note := tools.NewTool("note", "Take a note.", tools.ToolArgs[notes](noteArgs{}))
+	note.NeedsApproval = true
+	researcher := types.NewAgent[notes]("researcher", model)
+	researcher.WithTools(note)
+	agent := types.NewAgent[notes]("lead", model)
+	agent.WithTools(note, AgentTool(*researcher, "research", "Research a question."))
+
+	ctx := &notes{}
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var requests []ApprovalRequestedEvent
+	for event := range resp.Stream() {
+		request, ok := event.ApprovalRequested()
+		if !ok {
+			continue
+		}
+		if requests = append(requests, *request); len(requests) < 2 {
+			continue
+		}
+		if err := resp.Approve("call_1"); !errors.Is(err, ErrAmbiguousApproval) {
+			t.Fatalf("expected ErrAmbiguousApproval, got %v", err)
+		}
+		for _, request := range requests {
+			decision := ApprovalDecision{Approved: request.RunID == resp.RunID()}
+			if err := resp.ResolveInRun(request.RunID, request.ToolCallID, decision); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+
+	if status := resp.Status(); status != StatusCompleted {
+		t.Fatalf("expected completed run, got %v: %v", status, resp.Err())
+	}
+	if len(requests) != 2 || requests[0].RunID == requests[1].RunID {
+		t.Fatalf("expected a request from each run, got %+v", requests)
+	}
+	if !slices.Equal(ctx.taken, []string{"lead"}) {
+		t.Fatalf("expected only the lead's call to run, got %v", ctx.taken)
+	}
+}
+
+func TestAgentToolUsage(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []RunOption
+		status RunStatus
+		total  int
+	}{
+		{"counted", nil, StatusCompleted, 25},
+		{"limited", []RunOption{WithMaxTotalTokens(12)}, StatusFailed, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := func(tokens int) *types.Usage { return &types.Usage{TotalTokens: tokens} }
+			provider := &fakeProvider{turns: [][]types.CompletionChunk{
+				{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "research", Args: `{"prompt":"look it up"}`}}, Usage: used(10)}},
+				{{Content: "found it", Usage: used(5)}},
+				{{Content: "done", Usage: used(10)}},
+			}}
+			model := types.ModelConfig{Model: "fake", Provider: provider}
+			researcher := types.NewAgent[notes]("researcher", model)
+			agent := types.NewAgent[notes]("lead", model)
+			agent.WithTools(AgentTool(*researcher, "research", "Research a question."))
+
+			resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &notes{}, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if status := resp.Status(); status != tt.status {
+				t.Fatalf("expected %v run, got %v: %v", tt.status, status, resp.Err())
+			}
+			if tt.status == StatusFailed && !errors.Is(resp.Err(), ErrMaxTokensExceeded) {
+				t.Fatalf("expected ErrMaxTokensExceeded, got %v", resp.Err())
+			}
+			if total := resp.Usage().TotalTokens; total != tt.total {
+				t.Fatalf("expected %d tokens in total, got %d", tt.total, total)
+			}
+			if byAgent := resp.UsageByAgent(); byAgent["researcher"].TotalTokens != 5 {
+				t.Fatalf("expected the researcher's tokens to be counted, got %+v", byAgent)
+			}
+		})
+	}
+}
+
+// validatingProvider is a fakeProvider counting model validations.
+type validatingProvider struct {
+	*fakeProvider
+	validations int
+}
+
+func (p *validatingProvider) ValidateModel(context.Context, types.ModelConfig) error {
+	p.validations++
+	return nil
+}
+
+func TestModelValidatedOncePerRun(t *testing.T) {
+	provider := &validatingProvider{fakeProvider: &fakeProvider{turns: [][]types.CompletionChunk{
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "research", Args: `{"prompt":"look it up"}`}}}},
+		{{Content: "found it"}},
+		{{Content: "done"}},
+	}}}
+	model := types.ModelConfig{Model: "fake", Provider: provider}
+	researcher := types.NewAgent[notes]("researcher", model)
+	agent := types.NewAgent[notes]("lead", model)
+	agent.WithTools(AgentTool(*researcher, "research", "Research a question."))
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &notes{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := resp.Status(); status != StatusCompleted {
+		t.Fatalf("expected completed run, got %v: %v", status, resp.Err())
+	}
+	if provider.validations != 1 {
+		t.Fatalf("expected the model to be validated once, got %d validations", provider.validations)
+	}
+}
+
+func TestAgentToolMaxTurns(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "research", Args: `{"prompt":"look it up"}`}}}},
+		{{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_2", Name: "echo", Args: `{"text":"hi"}`}}}},
+		{{Content: "done"}},
+	}}
+	model := types.ModelConfig{Model: "fake", Provider: provider}
+	researcher := types.NewAgent[struct{}]("researcher", model)
+	researcher.WithTools(tools.NewTool("echo", "Echo text back.", tools.ToolArgs[struct{}](echoArgs{})))
+	agent := types.NewAgent[struct{}]("lead", model)
+	agent.WithTools(AgentTool(*researcher, "research", "Research a question.", WithAgentToolMaxTurns(1)))
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := resp.Status(); status != StatusCompleted {
+		t.Fatalf("expected completed run, got %v: %v", status, resp.Err())
+	}
+	result := resp.FinalConversation()[2]
+	if result.Role != types.Tool || !strings.Contains(result.Content, "agent researcher failed: max turns exceeded") {
+		t.Fatalf("unexpected tool result %+v", result)
+	}
+}
+
+func TestRunFailsOnInstructionErrors(t *testing.T) {
+	provider := &fakeProvider{turns: [][]types.CompletionChunk{{{Content: "hi"}}}}
+	agent := types.NewAgent[struct{}]("tester", types.ModelConfig{Model: "fake", Provider: provider}).
+		WithInstructionsString("You help {{ .User }}.")
+
+	resp, err := Run(context.Background(), *agent, Input{OfString: "hello"}, &struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := resp.Status(); status != StatusFailed || !errors.Is(resp.Err(), ErrInstructions) {
+		t.Fatalf("expected ErrInstructions, got %v: %v", status, resp.Err())
+	}
+	if len(provider.requests) != 0 {
+		t.Fatalf("expected no request to be sent")
+	}
+}
@@ -0,0 +1,172 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// ErrUnknownAgent is returned when a run is resumed with an agent missing
+// from the registry.
+var ErrUnknownAgent = errors.New("unknown agent")
+
+// RunState is a snapshot of a run, recorded when it finishes. It marshals to
+// JSON so a stopped run, for instance one waiting for approval, can be saved
+// and continued later with Resume.
+type RunState struct {
+	RunID string `json:"run_id"`
+	// Agent is the name of the agent that was active.
+	Agent    string          `json:"agent"`
+	Messages []types.Message `json:"messages"`
+	// AgentView is what the active agent was shown of the first ViewFrom
+	// messages when it received a handoff.
+	AgentView []types.Message `json:"agent_view,omitempty"`
+	ViewFrom  int             `json:"view_from,omitempty"`
+	// Callers are the agents waiting for a handoff to return, innermost
+	// last.
+	Callers []HandoffCaller `json:"callers,omitempty"`
+	// HandoffChain lists the agents that have been active, in order.
+	HandoffChain []string `json:"handoff_chain,omitempty"`
+	// PendingToolCalls were requested by the model but did not run before
+	// the run was stopped. Resume runs them before calling the model again.
+	PendingToolCalls []types.ToolCall `json:"pending_tool_calls,omitempty"`
+	Turns            int              `json:"turns"`
+	ToolCalls        int              `json:"tool_calls"`
+	Usage            types.Usage      `json:"usage"`
+	// AgentUsage breaks Usage down by agent name.
+	AgentUsage map[string]types.Usage `json:"agent_usage,omitempty"`
+	CostUSD    float64                `json:"cost_usd"`
+}
+
+// HandoffCaller is an agent waiting for a handoff to return, with what it was
+// shown of the first ViewFrom messages.
+type HandoffCaller struct {
+	Agent    string          `json:"agent"`
+	View     []types.Message `json:"view,omitempty"`
+	ViewFrom int             `json:"view_from,omitempty"`
+}
+
+// withoutInterrupted returns messages, the end of the conversation, without
+// the placeholder results of tool calls interrupted by cancellation, which
+// run again on resume. Tool call IDs may repeat across turns, so only the
+// results following the last assistant message, those of the interrupted
+// turn, are looked at.
+func (e *execution[Context]) withoutInterrupted(messages []types.Message) []types.Message {
+	interrupted := make(map[string]bool, len(e.interrupted))
+	for _, toolcall := range e.interrupted {
+		interrupted[toolcall.ID] = true
+	}
+	turn := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == types.Assistant {
+			turn = i + 1
+			break
+		}
+	}
+	kept := slices.Clone(messages[:turn])
+	for _, msg := range messages[turn:] {
+		if msg.Role == types.Tool && interrupted[msg.ID] {
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	return kept
+}
+
+// state records the run as a RunState. Tool calls interrupted by
+// cancellation become pending again instead of keeping their placeholder
+// results.
+func (e *execution[Context]) state() RunState {
+	callers := make([]HandoffCaller, len(e.callers))
+	for i, caller := range e.callers {
+		callers[i] = HandoffCaller{Agent: caller.agent.Name, View: caller.view, ViewFrom: caller.viewFrom}
+	}
+
+	return RunState{
+		RunID:            e.runID,
+		Agent:            e.agent.Name,
+		Messages:         e.withoutInterrupted(e.messages),
+		AgentView:        e.view,
+		ViewFrom:         e.viewFrom,
+		Callers:          callers,
+		HandoffChain:     slices.Clone(e.chain),
+		PendingToolCalls: slices.Clone(e.interrupted),
+		Turns:            e.turns,
+		ToolCalls:        e.toolCalls,
+		Usage:            e.usage,
+		AgentUsage:       maps.Clone(e.agentUsage),
+		CostUSD:          e.cost,
+	}
+}
+
+// State waits for the run to finish and returns a snapshot from which it can
+// be resumed.
+func (ar *AgentResponse) State() RunState {
+	ar.waitForStreamCompletion()
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	state := ar.result.state
+	state.Messages = slices.Clone(state.Messages)
+	state.AgentView = slices.Clone(state.AgentView)
+	state.Callers = slices.Clone(state.Callers)
+	state.HandoffChain = slices.Clone(state.HandoffChain)
+	state.PendingToolCalls = slices.Clone(state.PendingToolCalls)
+	state.AgentUsage = maps.Clone(state.AgentUsage)
+	return state
+}
+
+// Resume continues a run from state, keeping its run ID, conversation,
+// counters and usage. The active agent, and those waiting for it to return,
+// are looked up by name in registry.
+// Pending tool calls run first, going through approval again if the agent's
+// policy requires it. Limits set by opts apply to the run as a whole, the
+// part before the state was taken included. With a session, only the
+// messages added after resuming are saved to it.
+func Resume[Context any](runCtx context.Context, registry *types.AgentRegistry[Context], state RunState, ctx *Context, opts ...RunOption) (*AgentResponse, error) {
+	found, ok := registry.Lookup(state.Agent)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownAgent, state.Agent)
+	}
+	agent := *found
+	callers := make([]handoffFrame[Context], len(state.Callers))
+	for i, caller := range state.Callers {
+		found, ok := registry.Lookup(caller.Agent)
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownAgent, caller.Agent)
+		}
+		callers[i] = handoffFrame[Context]{agent: *found, view: caller.View, viewFrom: caller.ViewFrom}
+	}
+
+	logger := agent.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	config := RunConfig{}
+	if err := config.Apply(opts...); err != nil {
+		return nil, err
+	}
+
+	logger.Info("resuming agent run",
+		"run_id", state.RunID,
+		"agent_name", agent.Name,
+		"message_count", len(state.Messages),
+		"pending_tool_calls", len(state.PendingToolCalls))
+
+	if agent.Hooks != nil && agent.Hooks.BeforeRun != nil {
+		if err := agent.Hooks.BeforeRun(ctx); err != nil {
+			logger.Error("BeforeRun hook failed", "error", err)
+			return nil, fmt.Errorf("BeforeRun hook failed: %w", err)
+		}
+	}
+
+	if state.RunID == "" {
+		state.RunID = newRunID()
+	}
+	return start(runCtx, agent, callers, state, len(state.Messages), ctx, config, logger)
+}
@@ -0,0 +1,309 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/logkn/agents-go/internal/tools"
+	"github.com/logkn/agents-go/internal/tracing"
+	"github.com/logkn/agents-go/internal/types"
+	"github.com/logkn/agents-go/internal/utils"
+)
+
+// errToolCallCancelled is recorded as the result of tool calls skipped
+// because the run was cancelled.
+var errToolCallCancelled = errors.New("tool call cancelled")
+
+// toolOutcome is the result of executing a single tool call. Calls that
+// could not run, such as calls to unknown tools or calls vetoed by a hook,
+// have an error as their result so the model learns why.
+type toolOutcome struct {
+	name   string
+	result any
+	// nested are the runs the call started, such as an agent run as a tool.
+	nested []*AgentResponse
+}
+
+// skipToolCalls answers tool calls that will not run with reason, keeping
+// the transcript valid for later turns.
+func (e *execution[Context]) skipToolCalls(toolcalls []types.ToolCall, reason error) {
+	for _, toolcall := range toolcalls {
+		e.appendMessage(types.NewToolMessage(toolcall.ID, reason))
+	}
+}
+
+// findTool returns the agent's tool with the given name. Handoffs are
+// resolved separately, by findHandoff.
+func findTool[Context any](agent types.Agent[Context], name string) (tools.Tool[Context], bool) {
+	for _, tool := range agent.Tools {
+		if tool.CompleteName() == name {
+			return tool, true
+		}
+	}
+	return tools.Tool[Context]{}, false
+}
+
+// runToolCalls executes the tool calls of an assistant message. Consecutive
+// calls to tools that allow it run concurrently, up to the run's
+// ToolConcurrency, while handoffs and Sequential tools run on their own.
+// Results are recorded in the original call order. A handoff takes effect
+// once every call has been answered; only the first one is followed.
+func (e *execution[Context]) runToolCalls(toolcalls []types.ToolCall) (err error) {
+	// tools are resolved against the agent that requested them
+	agent := e.agent
+
+	var transfer *handoffTransfer[Context]
+	defer func() {
+		if transfer == nil {
+			return
+		}
+		if handoffErr := e.completeHandoff(*transfer); err == nil {
+			err = handoffErr
+		}
+	}()
+
+	for len(toolcalls) > 0 {
+		// once cancelled, skip the remaining calls
+		if e.runCtx.Err() != nil {
+			e.skipToolCalls(toolcalls, errToolCallCancelled)
+			e.interrupted = append(e.interrupted, toolcalls...)
+			return nil
+		}
+
+		batch := e.nextToolBatch(agent, toolcalls)
+		if limit := e.config.MaxToolCalls; limit > 0 {
+			remaining := limit - e.toolCalls
+			if remaining <= 0 {
+				e.skipToolCalls(toolcalls, ErrMaxToolCallsExceeded)
+				return fmt.Errorf("%w: limit of %d tool calls reached", ErrMaxToolCallsExceeded, limit)
+			}
+			batch = batch[:min(len(batch), remaining)]
+		}
+		toolcalls = toolcalls[len(batch):]
+		e.toolCalls += len(batch)
+
+		// Check if this is a handoff tool
+		if handoff, returning := e.findHandoff(agent, batch[0].Name); handoff != nil {
+			if transfer != nil {
+				e.appendMessage(types.NewToolMessage(batch[0].ID,
+					"Not transferred: already transferring to "+transfer.handoff.Agent.Name+" agent"))
+				continue
+			}
+			if transfer, err = e.acceptHandoff(*handoff, returning, batch[0]); err != nil {
+				e.skipToolCalls(toolcalls, err)
+				return err
+			}
+			continue
+		}
+
+		outcomes := e.executeBatch(agent, batch)
+		for i, toolcall := range batch {
+			outcome := outcomes[i]
+			if outcome.result == errToolCallCancelled {
+				// it will run when the run is resumed
+				e.interrupted = append(e.interrupted, toolcall)
+				e.toolCalls--
+			}
+			e.appendMessage(types.NewToolMessage(toolcall.ID, outcome.result))
+			e.emit(toolEvent(ToolResult{
+				Name:       outcome.name,
+				Content:    outcome.result,
+				ToolCallID: toolcall.ID,
+			}))
+			e.addNestedUsage(outcome.nested)
+		}
+		if limit := e.config.MaxTotalTokens; limit > 0 && e.usage.TotalTokens > limit {
+			e.skipToolCalls(toolcalls, ErrMaxTokensExceeded)
+			return fmt.Errorf("%w: used %d of %d tokens", ErrMaxTokensExceeded, e.usage.TotalTokens, limit)
+		}
+	}
+	return nil
+}
+
+// addNestedUsage adds the usage of runs started by a tool call to the run's
+// own. Runs the tool left going are not waited for, nor counted.
+func (e *execution[Context]) addNestedUsage(runs []*AgentResponse) {
+	for _, resp := range runs {
+		select {
+		case <-resp.done:
+		default:
+			continue
+		}
+		resp.mu.Lock()
+		result := resp.result
+		resp.mu.Unlock()
+
+		e.usage = e.usage.Add(result.usage)
+		if e.agentUsage == nil {
+			e.agentUsage = map[string]types.Usage{}
+		}
+		for agent, used := range result.agentUsage {
+			e.agentUsage[agent] = e.agentUsage[agent].Add(used)
+		}
+		e.cost += result.cost
+	}
+}
+
+// nextToolBatch returns the leading calls that can run together: a single
+// handoff or Sequential tool call, or a run of calls to concurrent tools.
+func (e *execution[Context]) nextToolBatch(agent types.Agent[Context], toolcalls []types.ToolCall) []types.ToolCall {
+	runsAlone := func(call types.ToolCall) bool {
+		if handoff, _ := e.findHandoff(agent, call.Name); handoff != nil {
+			return true
+		}
+		tool, found := findTool(agent, call.Name)
+		return found && tool.Sequential
+	}
+
+	if runsAlone(toolcalls[0]) {
+		return toolcalls[:1]
+	}
+	end := 1
+	for end < len(toolcalls) && !runsAlone(toolcalls[end]) {
+		end++
+	}
+	return toolcalls[:end]
+}
+
+// executeBatch runs the calls concurrently and returns their outcomes in call
+// order.
+func (e *execution[Context]) executeBatch(agent types.Agent[Context], batch []types.ToolCall) []toolOutcome {
+	outcomes := make([]toolOutcome, len(batch))
+	if len(batch) == 1 {
+		outcomes[0] = e.executeTool(agent, batch[0])
+		return outcomes
+	}
+
+	concurrency := e.config.ToolConcurrency
+	if concurrency <= 0 {
+		concurrency = len(batch)
+	}
+	semaphore := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, toolcall := range batch {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			outcomes[i] = e.executeTool(agent, toolcall)
+		}()
+	}
+	wg.Wait()
+	return outcomes
+}
+
+// executeTool runs a single tool call along with the agent's tool hooks,
+// first waiting for approval if the agent's policy requires it. It may be
+// called concurrently.
+func (e *execution[Context]) executeTool(agent types.Agent[Context], toolcall types.ToolCall) toolOutcome {
+	logger := e.logger
+	ctx := e.ctx
+	funcname := toolcall.Name
+	logger.Debug("executing tool",
+		"tool_name", funcname,
+		"tool_call_id", toolcall.ID,
+		"args_length", len(toolcall.Args))
+
+	span := e.span.StartChild(tracing.KindTool, funcname)
+	defer span.End()
+	span.SetAttribute("tool_call_id", toolcall.ID)
+
+	tool, found := findTool(agent, funcname)
+	if found && e.needsApproval(agent, tool, toolcall) {
+		decision, err := e.awaitApproval(toolcall)
+		switch {
+		case err != nil:
+			span.SetError(err)
+			return toolOutcome{name: funcname, result: errToolCallCancelled}
+		case !decision.Approved:
+			rejected := decision.err()
+			logger.Info("tool call rejected", "tool_name", funcname, "tool_call_id", toolcall.ID)
+			span.SetAttribute("approval", "rejected")
+			span.SetError(rejected)
+			return toolOutcome{name: tool.CompleteName(), result: rejected}
+		case decision.Args != "":
+			span.SetAttribute("approval", "edited")
+			toolcall.Args = decision.Args
+		default:
+			span.SetAttribute("approval", "approved")
+		}
+	}
+	span.SetInput(toolcall.Args)
+
+	var args map[string]any
+	json.Unmarshal([]byte(toolcall.Args), &args)
+	e.emit(toolCallStartedEvent(ToolCallStartedEvent{
+		ToolCallID: toolcall.ID,
+		Name:       funcname,
+		Args:       args,
+		RawArgs:    toolcall.Args,
+	}))
+	started := time.Now()
+	finished := func(result any, err error) {
+		span.SetError(err)
+		e.emit(toolCallFinishedEvent(ToolCallFinishedEvent{
+			ToolCallID: toolcall.ID,
+			Name:       funcname,
+			Result:     result,
+			Duration:   time.Since(started),
+			Err:        err,
+		}))
+	}
+
+	if !found {
+		logger.Error("tool not found", "tool_name", funcname)
+		err := fmt.Errorf("tool %q not found", funcname)
+		finished(nil, err)
+		return toolOutcome{name: funcname, result: err}
+	}
+
+	// Execute BeforeToolCall hook
+	if agent.Hooks != nil && agent.Hooks.BeforeToolCall != nil {
+		if err := agent.Hooks.BeforeToolCall(ctx, funcname, toolcall.Args); err != nil {
+			logger.Error("BeforeToolCall hook failed", "error", err, "tool_name", funcname)
+			err = fmt.Errorf("tool call blocked: %w", err)
+			finished(nil, err)
+			return toolOutcome{name: tool.CompleteName(), result: err}
+		}
+	}
+
+	// runs started by the tool are traced inside its span and nested in
+	// this run
+	var nestedMu sync.Mutex
+	var nested []*AgentResponse
+	toolCtx := tracing.ContextWithSpan(e.runCtx, span)
+	toolCtx = contextWithParentRun(toolCtx, &parentRun{
+		toolCallID: toolcall.ID,
+		deliver:    e.deliver,
+		response:   e.response,
+		started: func(resp *AgentResponse) {
+			nestedMu.Lock()
+			defer nestedMu.Unlock()
+			nested = append(nested, resp)
+		},
+	})
+	result := tool.RunOnArgsWithContext(toolCtx, toolcall.Args, ctx)
+	span.SetOutput(utils.AsString(result))
+	resultErr, _ := result.(error)
+	finished(result, resultErr)
+
+	// Execute AfterToolCall hook
+	if agent.Hooks != nil && agent.Hooks.AfterToolCall != nil {
+		if err := agent.Hooks.AfterToolCall(ctx, funcname, result); err != nil {
+			logger.Error("AfterToolCall hook failed", "error", err, "tool_name", funcname)
+		}
+	}
+
+	logger.Info("tool execution completed",
+		"tool_name", funcname,
+		"tool_call_id", toolcall.ID)
+
+	nestedMu.Lock()
+	defer nestedMu.Unlock()
+	return toolOutcome{name: tool.CompleteName(), result: result, nested: nested}
+}
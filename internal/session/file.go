@@ -0,0 +1,122 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// validID restricts session IDs to names that are safe as file names.
+var validID = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// fileLocks serializes access to each session file within the process, so
+// sessions opened separately for the same file can still be shared.
+var fileLocks sync.Map
+
+// FileStore keeps one JSONL file per session in a directory, one message per
+// line.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a store keeping its sessions in dir, which is created
+// if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("session: failed to create %s: %w", dir, err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Session returns the session with the given ID, creating its file on first
+// write.
+func (s *FileStore) Session(id string) (*File, error) {
+	if !validID.MatchString(id) || id == "." || id == ".." {
+		return nil, fmt.Errorf("session: invalid session ID %q", id)
+	}
+	path, err := filepath.Abs(filepath.Join(s.dir, id+".jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	lock, _ := fileLocks.LoadOrStore(path, &sync.Mutex{})
+	return &File{path: path, mu: lock.(*sync.Mutex)}, nil
+}
+
+// File is a session stored as a JSONL file.
+type File struct {
+	path string
+	mu   *sync.Mutex
+}
+
+func (f *File) Load(_ context.Context) ([]types.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("session: failed to read %s: %w", f.path, err)
+	}
+
+	messages := []types.Message{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var msg types.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return nil, fmt.Errorf("session: %s line %d: %w", f.path, line, err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, scanner.Err()
+}
+
+func (f *File) Append(_ context.Context, messages ...types.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, msg := range messages {
+		if err := encoder.Encode(msg); err != nil {
+			return fmt.Errorf("session: failed to encode message: %w", err)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("session: failed to open %s: %w", f.path, err)
+	}
+	// a single write keeps the batch contiguous even if another process
+	// appends to the same file
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return fmt.Errorf("session: failed to write %s: %w", f.path, err)
+	}
+	return file.Close()
+}
+
+func (f *File) Clear(_ context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("session: failed to clear %s: %w", f.path, err)
+	}
+	return nil
+}
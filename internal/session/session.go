@@ -0,0 +1,42 @@
+// Package session provides the built-in Session backends that persist a
+// conversation across runs.
+package session
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// Memory keeps a conversation in memory for the lifetime of the process.
+type Memory struct {
+	mu       sync.Mutex
+	messages []types.Message
+}
+
+// NewMemory returns an empty in-memory session.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Load(_ context.Context) ([]types.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.messages), nil
+}
+
+func (m *Memory) Append(_ context.Context, messages ...types.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, messages...)
+	return nil
+}
+
+func (m *Memory) Clear(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+	return nil
+}
@@ -0,0 +1,70 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/logkn/agents-go/internal/types"
+)
+
+func TestFileSession(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Session("../escape"); err == nil {
+		t.Fatalf("expected path-like IDs to be rejected")
+	}
+
+	sess, _ := store.Session("chat-1")
+	if history, err := sess.Load(ctx); err != nil || len(history) != 0 {
+		t.Fatalf("expected an empty new session, got %v %v", history, err)
+	}
+
+	call := types.ToolCall{ID: "call_1", Name: "echo", Args: `{"text":"hi"}`}
+	sess.Append(ctx, types.NewUserMessage("hello"), types.NewAssistantMessage("", "bot", []types.ToolCall{call}))
+
+	// a second handle on the same file sees the same conversation
+	again, _ := store.Session("chat-1")
+	history, err := again.Load(ctx)
+	if err != nil || len(history) != 2 || history[1].ToolCalls[0] != call {
+		t.Fatalf("unexpected history %+v %v", history, err)
+	}
+
+	if err := again.Clear(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if history, _ := sess.Load(ctx); len(history) != 0 {
+		t.Fatalf("expected cleared session, got %+v", history)
+	}
+}
+
+func TestFileSessionConcurrentAppends(t *testing.T) {
+	ctx := context.Background()
+	store, _ := NewFileStore(t.TempDir())
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sess, _ := store.Session("shared")
+			sess.Append(ctx, types.NewUserMessage(fmt.Sprint(i)), types.NewAssistantMessage(fmt.Sprint(i), "bot", nil))
+		}()
+	}
+	wg.Wait()
+
+	sess, _ := store.Session("shared")
+	history, err := sess.Load(ctx)
+	if err != nil || len(history) != 40 {
+		t.Fatalf("expected 40 messages, got %d %v", len(history), err)
+	}
+	for i := 0; i < len(history); i += 2 {
+		if history[i].Content != history[i+1].Content {
+			t.Fatalf("appends were interleaved at %d", i)
+		}
+	}
+}
@@ -2,6 +2,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -17,11 +18,31 @@ type ToolArgs[Context any] interface {
 	Run(ctx *Context) any
 }
 
+// CancellableToolArgs is implemented by tool arguments that want the run's
+// context.Context, e.g. to abort long-running work when the run is cancelled.
+// When implemented, RunWithContext is called instead of Run.
+type CancellableToolArgs[Context any] interface {
+	RunWithContext(runCtx context.Context, ctx *Context) any
+}
+
+// ArgsDecoder is implemented by tool arguments that build themselves from the
+// JSON arguments of a call, e.g. to carry over unexported state. Other
+// arguments are unmarshaled into a zero value of their type.
+type ArgsDecoder[Context any] interface {
+	DecodeArgs(args string) (ToolArgs[Context], error)
+}
+
 // Tool describes an executable function that can be invoked by an agent.
+// Calls made in the same turn run concurrently unless Sequential is set, which
+// tools with side effects should use. Calls to tools with NeedsApproval set
+// wait for a person to approve them, unless the agent's approval policy says
+// otherwise.
 type Tool[Context any] struct {
-	Name        string
-	Description string
-	Args        ToolArgs[Context]
+	Name          string
+	Description   string
+	Args          ToolArgs[Context]
+	Sequential    bool
+	NeedsApproval bool
 }
 
 // CompleteName returns the explicit name if set or derives one from the
@@ -37,25 +58,57 @@ func (t Tool[Context]) CompleteName() string {
 	return strcase.SnakeCase(typeName)
 }
 
-// ToOpenAITool converts this tool into the format expected by the OpenAI SDK.
-func (t Tool[Context]) ToOpenAITool() openai.ChatCompletionToolParam {
+// Definition is the provider-agnostic description of a tool: its name,
+// description and the JSON schema of its arguments.
+type Definition struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+}
+
+// ToOpenAI converts the definition into the format expected by the OpenAI SDK.
+func (d Definition) ToOpenAI() openai.ChatCompletionToolParam {
+	return openai.ChatCompletionToolParam{
+		Function: openai.FunctionDefinitionParam{
+			Name:        d.Name,
+			Description: openai.String(d.Description),
+			Parameters:  d.Parameters,
+		},
+	}
+}
+
+// Definition describes this tool for an LLM provider.
+func (t Tool[Context]) Definition() Definition {
 	schema, err := utils.CreateSchema(t.Args)
 	if err != nil {
 		fmt.Println("Error creating schema for tool arguments:", err)
-		return openai.ChatCompletionToolParam{}
+		return Definition{}
 	}
-	return openai.ChatCompletionToolParam{
-		Function: openai.FunctionDefinitionParam{
-			Name:        t.CompleteName(),
-			Description: openai.String(t.Description),
-			Parameters:  schema,
-		},
+	return Definition{
+		Name:        t.CompleteName(),
+		Description: t.Description,
+		Parameters:  schema,
 	}
 }
 
+// ToOpenAITool converts this tool into the format expected by the OpenAI SDK.
+func (t Tool[Context]) ToOpenAITool() openai.ChatCompletionToolParam {
+	def := t.Definition()
+	if def.Name == "" {
+		return openai.ChatCompletionToolParam{}
+	}
+	return def.ToOpenAI()
+}
+
 // RunOnArgs unmarshals the provided JSON arguments and executes the tool with context.
 // This method should be used when the tool requires access to the execution context.
 func (t Tool[Context]) RunOnArgs(args string, ctx *Context) any {
+	return t.RunOnArgsWithContext(context.Background(), args, ctx)
+}
+
+// RunOnArgsWithContext is like RunOnArgs but hands runCtx to tools that
+// implement CancellableToolArgs.
+func (t Tool[Context]) RunOnArgsWithContext(runCtx context.Context, args string, ctx *Context) any {
 	// parse the args into the tool's args type
 
 	// Special handling for baseToolArgsAdapter to access the underlying baseToolArgs
@@ -70,6 +123,9 @@ func (t Tool[Context]) RunOnArgs(args string, ctx *Context) any {
 
 		// Dereference the pointer and cast to baseToolArgs
 		argsValue := reflect.ValueOf(argsInstancePtr).Elem().Interface()
+		if cancellable, ok := argsValue.(cancellableBaseToolArgs); ok {
+			return cancellable.RunWithContext(runCtx)
+		}
 		if baseArgs, ok := argsValue.(baseToolArgs); ok {
 			result := baseArgs.Run()
 			return result
@@ -79,16 +135,27 @@ func (t Tool[Context]) RunOnArgs(args string, ctx *Context) any {
 	}
 
 	// Regular tool handling
-	argsInstance := utils.NewInstance(t.Args)
+	var toolArgs ToolArgs[Context]
+	if decoder, ok := t.Args.(ArgsDecoder[Context]); ok {
+		decoded, err := decoder.DecodeArgs(args)
+		if err != nil {
+			return fmt.Sprintf("Error unmarshaling arguments: %v", err)
+		}
+		toolArgs = decoded
+	} else {
+		argsInstance := utils.NewInstance(t.Args)
 
-	// unmarshal JSON args into the instance
-	if err := json.Unmarshal([]byte(args), argsInstance); err != nil {
-		return fmt.Sprintf("Error unmarshaling arguments: %v", err)
+		// unmarshal JSON args into the instance
+		if err := json.Unmarshal([]byte(args), argsInstance); err != nil {
+			return fmt.Sprintf("Error unmarshaling arguments: %v", err)
+		}
+		toolArgs = argsInstance.(ToolArgs[Context])
 	}
 
-	toolArgs := argsInstance.(ToolArgs[Context])
-
 	// execute the tool
+	if cancellable, ok := toolArgs.(CancellableToolArgs[Context]); ok {
+		return cancellable.RunWithContext(runCtx, ctx)
+	}
 	result := toolArgs.Run(ctx)
 
 	return result
@@ -110,10 +177,18 @@ type baseToolArgs interface {
 	Run() any
 }
 
+// cancellableBaseToolArgs is the context-free counterpart of
+// CancellableToolArgs.
+type cancellableBaseToolArgs interface {
+	RunWithContext(runCtx context.Context) any
+}
+
 type BaseTool struct {
-	Name        string
-	Description string
-	Args        baseToolArgs
+	Name          string
+	Description   string
+	Args          baseToolArgs
+	Sequential    bool
+	NeedsApproval bool
 }
 
 // baseToolArgsAdapter adapts baseToolArgs to work with ToolArgs[Context]
@@ -127,8 +202,10 @@ func (p baseToolArgsAdapter[Context]) Run(ctx *Context) any {
 
 func CoerceBaseTool[Context any](base BaseTool) Tool[Context] {
 	return Tool[Context]{
-		Name:        base.Name,
-		Description: base.Description,
-		Args:        baseToolArgsAdapter[Context]{baseToolArgs: base.Args},
+		Name:          base.Name,
+		Description:   base.Description,
+		Args:          baseToolArgsAdapter[Context]{baseToolArgs: base.Args},
+		Sequential:    base.Sequential,
+		NeedsApproval: base.NeedsApproval,
 	}
 }
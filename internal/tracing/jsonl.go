@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// JSONLExporter writes each finished span as one JSON line.
+type JSONLExporter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewJSONLExporter returns an exporter writing spans to w.
+func NewJSONLExporter(w io.Writer) *JSONLExporter {
+	return &JSONLExporter{w: w}
+}
+
+// OpenJSONLExporter returns an exporter appending spans to the file at path,
+// creating it if needed. Shutdown closes the file.
+func OpenJSONLExporter(path string) (*JSONLExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	return &JSONLExporter{w: file, closer: file}, nil
+}
+
+func (e *JSONLExporter) OnStart(Span) {}
+
+func (e *JSONLExporter) OnEnd(span Span) {
+	line, err := json.Marshal(span)
+	if err != nil {
+		slog.Error("failed to encode span", "span", span.Name, "error", err)
+		return
+	}
+	line = append(line, '\n')
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.w.Write(line); err != nil {
+		slog.Error("failed to write span", "span", span.Name, "error", err)
+	}
+}
+
+func (e *JSONLExporter) Shutdown(context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closer == nil {
+		return nil
+	}
+	err := e.closer.Close()
+	e.closer = nil
+	return err
+}
@@ -0,0 +1,308 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultOTLPEndpoint is where a local OpenTelemetry collector accepts
+// traces over HTTP.
+const DefaultOTLPEndpoint = "http://localhost:4318/v1/traces"
+
+// OTLPConfig configures an OTLPExporter. Zero values select the defaults.
+type OTLPConfig struct {
+	// Endpoint is the collector's OTLP/HTTP traces URL.
+	Endpoint string
+	// Headers are sent with every export, e.g. for authentication.
+	Headers map[string]string
+	// ServiceName identifies the application in the collector.
+	ServiceName string
+	// BatchSize is how many finished spans are sent together.
+	BatchSize int
+	// FlushInterval bounds how long a finished span waits to be sent.
+	FlushInterval time.Duration
+	// Client sends the requests; http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// OTLPExporter sends spans to an OpenTelemetry collector using OTLP over
+// HTTP with JSON encoding. Spans are batched and sent in the background.
+type OTLPExporter struct {
+	config OTLPConfig
+
+	mu      sync.Mutex
+	pending []Span
+	flush   chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	closed  bool
+}
+
+// NewOTLPExporter returns an exporter sending spans as configured.
+func NewOTLPExporter(config OTLPConfig) *OTLPExporter {
+	if config.Endpoint == "" {
+		config.Endpoint = DefaultOTLPEndpoint
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = "agents-go"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 64
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 5 * time.Second
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+
+	e := &OTLPExporter{
+		config:  config,
+		flush:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go e.loop()
+	return e
+}
+
+func (e *OTLPExporter) OnStart(Span) {}
+
+func (e *OTLPExporter) OnEnd(span Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	e.pending = append(e.pending, span)
+	if len(e.pending) >= e.config.BatchSize {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Shutdown sends the remaining spans and stops the exporter.
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mu.Unlock()
+
+	close(e.done)
+	select {
+	case <-e.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return e.send(ctx, e.take())
+}
+
+// loop sends batches until the exporter is shut down.
+func (e *OTLPExporter) loop() {
+	defer close(e.stopped)
+	ticker := time.NewTicker(e.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.flush:
+		case <-e.done:
+			return
+		}
+		if err := e.send(context.Background(), e.take()); err != nil {
+			slog.Error("failed to export spans", "endpoint", e.config.Endpoint, "error", err)
+		}
+	}
+}
+
+// take removes and returns the pending spans.
+func (e *OTLPExporter) take() []Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	spans := e.pending
+	e.pending = nil
+	return spans
+}
+
+func (e *OTLPExporter) send(ctx context.Context, spans []Span) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(otlpRequest(e.config.ServiceName, spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("collector returned %s: %s", resp.Status, message)
+	}
+	return nil
+}
+
+// The types below mirror the JSON encoding of the OTLP trace export request.
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+// OTLP span kinds and status codes.
+const (
+	otlpKindInternal = 1
+	otlpKindClient   = 3
+
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+func otlpRequest(serviceName string, spans []Span) otlpExportRequest {
+	converted := make([]otlpSpan, len(spans))
+	for i, span := range spans {
+		converted[i] = toOTLP(span)
+	}
+	return otlpExportRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			attribute("service.name", serviceName),
+		}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/logkn/agents-go"},
+			Spans: converted,
+		}},
+	}}}
+}
+
+func toOTLP(span Span) otlpSpan {
+	kind := otlpKindInternal
+	if span.Kind == KindGeneration {
+		kind = otlpKindClient
+	}
+	status := otlpStatus{Code: otlpStatusOK}
+	if span.Error != "" {
+		status = otlpStatus{Code: otlpStatusError, Message: span.Error}
+	}
+
+	attributes := []otlpAttribute{attribute("agents.span.kind", string(span.Kind))}
+	if span.Input != "" {
+		attributes = append(attributes, attribute("agents.input", span.Input))
+	}
+	if span.Output != "" {
+		attributes = append(attributes, attribute("agents.output", span.Output))
+	}
+	if span.Usage != nil {
+		attributes = append(attributes,
+			attribute("gen_ai.usage.input_tokens", span.Usage.InputTokens),
+			attribute("gen_ai.usage.output_tokens", span.Usage.OutputTokens))
+	}
+	for key, value := range span.Attributes {
+		attributes = append(attributes, attribute(key, value))
+	}
+
+	return otlpSpan{
+		TraceID:           span.TraceID,
+		SpanID:            span.SpanID,
+		ParentSpanID:      span.ParentID,
+		Name:              span.Name,
+		Kind:              kind,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		Attributes:        attributes,
+		Status:            status,
+	}
+}
+
+// attribute converts a value to the closest OTLP attribute type.
+func attribute(key string, value any) otlpAttribute {
+	var v otlpValue
+	switch value := value.(type) {
+	case string:
+		v.StringValue = &value
+	case bool:
+		v.BoolValue = &value
+	case int:
+		s := strconv.Itoa(value)
+		v.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(value, 10)
+		v.IntValue = &s
+	case float64:
+		v.DoubleValue = &value
+	default:
+		s := fmt.Sprint(value)
+		v.StringValue = &s
+	}
+	return otlpAttribute{Key: key, Value: v}
+}
@@ -0,0 +1,267 @@
+// Package tracing records runs as traces of nested, timed spans and hands
+// them to processors for export.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// SpanKind tells what operation a span covers.
+type SpanKind string
+
+const (
+	// KindRun is the root span of a trace, covering a whole run.
+	KindRun SpanKind = "run"
+	// KindGeneration covers one LLM request.
+	KindGeneration SpanKind = "generation"
+	// KindTool covers one tool execution.
+	KindTool SpanKind = "tool"
+	// KindHandoff covers a transfer between agents.
+	KindHandoff SpanKind = "handoff"
+	// KindGuardrail covers one guardrail check.
+	KindGuardrail SpanKind = "guardrail"
+)
+
+// Span is the record of one timed operation. Spans of a run share its
+// TraceID; ParentID links a span to the one it is nested in.
+type Span struct {
+	TraceID    string         `json:"trace_id"`
+	SpanID     string         `json:"span_id"`
+	ParentID   string         `json:"parent_id,omitempty"`
+	Kind       SpanKind       `json:"kind"`
+	Name       string         `json:"name"`
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+	Input      string         `json:"input,omitempty"`
+	Output     string         `json:"output,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Usage      *types.Usage   `json:"usage,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// Duration returns how long the span lasted, or zero if it has not ended.
+func (s Span) Duration() time.Duration {
+	if s.End.IsZero() {
+		return 0
+	}
+	return s.End.Sub(s.Start)
+}
+
+// Processor receives spans as they start and end. Processors are called
+// from the goroutines doing the traced work, so they must be safe for
+// concurrent use and should not block.
+type Processor interface {
+	OnStart(span Span)
+	OnEnd(span Span)
+	// Shutdown flushes buffered spans and releases the processor.
+	Shutdown(ctx context.Context) error
+}
+
+// Redactor rewrites a span before processors see it, for example to mask
+// secrets in inputs and outputs.
+type Redactor func(span Span) Span
+
+// OmitContent is a Redactor that drops inputs and outputs, keeping only
+// timing, usage and errors.
+func OmitContent(span Span) Span {
+	span.Input = ""
+	span.Output = ""
+	return span
+}
+
+// Tracer starts spans and delivers them to its processors. A nil Tracer is
+// valid and records nothing.
+type Tracer struct {
+	processors []Processor
+	redact     Redactor
+}
+
+// NewTracer returns a tracer exporting to processors.
+func NewTracer(processors ...Processor) *Tracer {
+	return &Tracer{processors: processors}
+}
+
+// WithRedactor returns the tracer with redact applied to every span it
+// exports.
+func (t *Tracer) WithRedactor(redact Redactor) *Tracer {
+	t.redact = redact
+	return t
+}
+
+// Start begins the root span of a new trace.
+func (t *Tracer) Start(kind SpanKind, name string) *ActiveSpan {
+	if t == nil {
+		return nil
+	}
+	return t.start(newID(16), "", kind, name)
+}
+
+// Shutdown shuts down every processor of the tracer.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	var errs []error
+	for _, processor := range t.processors {
+		errs = append(errs, processor.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (t *Tracer) start(traceID, parentID string, kind SpanKind, name string) *ActiveSpan {
+	span := &ActiveSpan{
+		tracer: t,
+		span: Span{
+			TraceID:  traceID,
+			SpanID:   newID(8),
+			ParentID: parentID,
+			Kind:     kind,
+			Name:     name,
+			Start:    time.Now(),
+		},
+	}
+	t.export(span.snapshot(), Processor.OnStart)
+	return span
+}
+
+func (t *Tracer) export(span Span, deliver func(Processor, Span)) {
+	if t.redact != nil {
+		span = t.redact(span)
+	}
+	for _, processor := range t.processors {
+		deliver(processor, span)
+	}
+}
+
+// ActiveSpan is a span in progress. Its methods are safe for concurrent use
+// and do nothing on a nil ActiveSpan, so untraced runs need no checks.
+type ActiveSpan struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	span  Span
+	ended bool
+}
+
+// StartChild begins a span nested in s.
+func (s *ActiveSpan) StartChild(kind SpanKind, name string) *ActiveSpan {
+	if s == nil {
+		return nil
+	}
+	return s.tracer.start(s.span.TraceID, s.span.SpanID, kind, name)
+}
+
+// TraceID returns the ID of the trace s belongs to.
+func (s *ActiveSpan) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.span.TraceID
+}
+
+// SetInput records what the operation was given.
+func (s *ActiveSpan) SetInput(input string) {
+	s.update(func(span *Span) { span.Input = input })
+}
+
+// SetOutput records what the operation produced.
+func (s *ActiveSpan) SetOutput(output string) {
+	s.update(func(span *Span) { span.Output = output })
+}
+
+// SetAttribute records a key-value detail of the operation.
+func (s *ActiveSpan) SetAttribute(key string, value any) {
+	s.update(func(span *Span) {
+		if span.Attributes == nil {
+			span.Attributes = map[string]any{}
+		}
+		span.Attributes[key] = value
+	})
+}
+
+// SetUsage records the tokens the operation consumed.
+func (s *ActiveSpan) SetUsage(usage types.Usage) {
+	s.update(func(span *Span) { span.Usage = &usage })
+}
+
+// SetError marks the operation as failed. A nil err is ignored.
+func (s *ActiveSpan) SetError(err error) {
+	if err == nil {
+		return
+	}
+	s.update(func(span *Span) { span.Error = err.Error() })
+}
+
+// End finishes the span and exports it. Later calls do nothing.
+func (s *ActiveSpan) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.span.End = time.Now()
+	span := s.snapshotLocked()
+	s.mu.Unlock()
+
+	s.tracer.export(span, Processor.OnEnd)
+}
+
+func (s *ActiveSpan) update(apply func(span *Span)) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		apply(&s.span)
+	}
+}
+
+func (s *ActiveSpan) snapshot() Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotLocked()
+}
+
+func (s *ActiveSpan) snapshotLocked() Span {
+	span := s.span
+	span.Attributes = maps.Clone(span.Attributes)
+	return span
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying span, so runs started with
+// it are nested in span instead of starting a new trace.
+func ContextWithSpan(ctx context.Context, span *ActiveSpan) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span carried by ctx, or nil.
+func SpanFromContext(ctx context.Context) *ActiveSpan {
+	span, _ := ctx.Value(spanKey{}).(*ActiveSpan)
+	return span
+}
+
+// newID returns a random hex ID of n bytes, the sizes OpenTelemetry uses for
+// trace and span IDs.
+func newID(n int) string {
+	id := make([]byte, n)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,154 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// recorder keeps the spans it is given.
+type recorder struct {
+	mu      sync.Mutex
+	started []Span
+	ended   []Span
+}
+
+func (r *recorder) OnStart(span Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started = append(r.started, span)
+}
+
+func (r *recorder) OnEnd(span Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ended = append(r.ended, span)
+}
+
+func (r *recorder) Shutdown(context.Context) error { return nil }
+
+func TestSpansNest(t *testing.T) {
+	rec := &recorder{}
+	tracer := NewTracer(rec)
+
+	run := tracer.Start(KindRun, "agent")
+	tool := run.StartChild(KindTool, "search")
+	tool.SetInput(`{"q":"go"}`)
+	tool.SetError(errors.New("not found"))
+	tool.End()
+	tool.End()
+	run.SetUsage(types.Usage{InputTokens: 3, OutputTokens: 2})
+	run.End()
+
+	if len(rec.started) != 2 || len(rec.ended) != 2 {
+		t.Fatalf("expected each span to start and end once, got %d and %d", len(rec.started), len(rec.ended))
+	}
+	child, root := rec.ended[0], rec.ended[1]
+	if child.TraceID != root.TraceID || child.ParentID != root.SpanID || root.ParentID != "" {
+		t.Fatalf("expected the tool span nested in the run span, got %+v and %+v", child, root)
+	}
+	if child.Error != "not found" || child.Input != `{"q":"go"}` || child.Duration() <= 0 {
+		t.Fatalf("unexpected tool span %+v", child)
+	}
+	if root.Usage == nil || root.Usage.InputTokens != 3 {
+		t.Fatalf("expected usage on the run span, got %+v", root.Usage)
+	}
+}
+
+func TestNilTracerRecordsNothing(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.Start(KindRun, "agent")
+	span.StartChild(KindTool, "search").End()
+	span.SetAttribute("key", "value")
+	span.End()
+	if SpanFromContext(ContextWithSpan(context.Background(), span)) != nil {
+		t.Fatalf("expected no span in the context")
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	rec := &recorder{}
+	tracer := NewTracer(rec).WithRedactor(OmitContent)
+
+	span := tracer.Start(KindGeneration, "model")
+	span.SetInput("secret prompt")
+	span.SetOutput("secret answer")
+	span.End()
+
+	if got := rec.ended[0]; got.Input != "" || got.Output != "" {
+		t.Fatalf("expected content to be redacted, got %+v", got)
+	}
+}
+
+func TestJSONLExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(NewJSONLExporter(&buf))
+	run := tracer.Start(KindRun, "agent")
+	run.StartChild(KindTool, "search").End()
+	run.End()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one line per finished span, got %q", buf.String())
+	}
+	var span Span
+	if err := json.Unmarshal([]byte(lines[1]), &span); err != nil {
+		t.Fatalf("invalid span line: %v", err)
+	}
+	if span.Kind != KindRun || span.Name != "agent" {
+		t.Fatalf("unexpected span %+v", span)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	requests := make(chan otlpExportRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req otlpExportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid export request: %v", err)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("missing header, got %q", r.Header.Get("Authorization"))
+		}
+		requests <- req
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(OTLPConfig{
+		Endpoint:    server.URL,
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+		ServiceName: "test",
+	})
+	tracer := NewTracer(exporter)
+	run := tracer.Start(KindRun, "agent")
+	generation := run.StartChild(KindGeneration, "model")
+	generation.SetError(errors.New("overloaded"))
+	generation.End()
+	run.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := <-requests
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected both spans in one batch, got %d", len(spans))
+	}
+	if spans[0].ParentSpanID != spans[1].SpanID || len(spans[0].TraceID) != 32 || len(spans[0].SpanID) != 16 {
+		t.Fatalf("unexpected span IDs %+v", spans)
+	}
+	if spans[0].Kind != otlpKindClient || spans[0].Status.Code != otlpStatusError || spans[0].Status.Message != "overloaded" {
+		t.Fatalf("unexpected generation span %+v", spans[0])
+	}
+	if name := req.ResourceSpans[0].Resource.Attributes[0].Value.StringValue; name == nil || *name != "test" {
+		t.Fatalf("expected the service name as a resource attribute")
+	}
+}
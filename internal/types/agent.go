@@ -2,6 +2,7 @@ package types
 
 import (
 	"log/slog"
+	"slices"
 
 	"github.com/logkn/agents-go/internal/tools"
 	"github.com/logkn/agents-go/internal/utils"
@@ -25,6 +26,20 @@ type Agent[Context any] struct {
 	Logger *slog.Logger
 	// Hooks define optional lifecycle callbacks
 	Hooks *LifecycleHooks[Context]
+	// OutputType, when set, is a value of the type the agent's final answer
+	// must decode into, e.g. Report{}. The answer is then constrained to the
+	// type's JSON schema instead of being free text.
+	OutputType any
+	// InputGuardrails check the user input of a run started with this agent
+	InputGuardrails []Guardrail[Context]
+	// OutputGuardrails check the agent's final answer
+	OutputGuardrails []Guardrail[Context]
+	// History trims the conversation sent to the model on each turn; nil
+	// sends all of it.
+	History HistoryStrategy
+	// ApprovalPolicy decides which tool calls wait for approval; nil holds
+	// the calls to tools marked NeedsApproval.
+	ApprovalPolicy ApprovalPolicy[Context]
 }
 
 func (a *Agent[Context]) WithBaseTools(baseTools ...tools.BaseTool) *Agent[Context] {
@@ -43,12 +58,29 @@ func (a *Agent[Context]) AllTools() []tools.Tool[Context] {
 	handoffTools := make([]tools.Tool[Context], len(a.Handoffs))
 	for i, handoff := range a.Handoffs {
 		handoffTools[i] = tools.Tool[Context]{
-			Name:        handoff.fullname(),
+			Name:        handoff.CompleteName(),
 			Description: handoff.description(),
 			Args:        handoffToolArgs[Context]{},
 		}
 	}
-	return append(a.Tools, handoffTools...)
+	return slices.Concat(a.Tools, handoffTools)
+}
+
+// ToolDefinitions describes the agent's tools and handoffs for an LLM
+// provider.
+func (a *Agent[Context]) ToolDefinitions() ([]tools.Definition, error) {
+	definitions := make([]tools.Definition, 0, len(a.Tools)+len(a.Handoffs))
+	for _, tool := range a.Tools {
+		definitions = append(definitions, tool.Definition())
+	}
+	for _, handoff := range a.Handoffs {
+		definition, err := handoff.Definition()
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, nil
 }
 
 func NewAgent[Context any](name string, model ModelConfig) *Agent[Context] {
@@ -89,3 +121,42 @@ func (a *Agent[Context]) WithInstructionsFile(instructions string) *Agent[Contex
 	a.Instructions = FileInstructions[Context](instructions)
 	return a
 }
+
+func (a *Agent[Context]) WithInstructionsFunc(fn func(ctx *Context, agent *Agent[Context]) (string, error)) *Agent[Context] {
+	a.Instructions = FuncInstructions(fn)
+	return a
+}
+
+// WithOutputType makes the agent answer with JSON decoding into the type of
+// outputType.
+func (a *Agent[Context]) WithOutputType(outputType any) *Agent[Context] {
+	a.OutputType = outputType
+	return a
+}
+
+// WithInputGuardrails returns the agent with guardrails checking its input.
+func (a *Agent[Context]) WithInputGuardrails(guardrails ...Guardrail[Context]) *Agent[Context] {
+	a.InputGuardrails = append(a.InputGuardrails, guardrails...)
+	return a
+}
+
+// WithOutputGuardrails returns the agent with guardrails checking its final
+// answer.
+func (a *Agent[Context]) WithOutputGuardrails(guardrails ...Guardrail[Context]) *Agent[Context] {
+	a.OutputGuardrails = append(a.OutputGuardrails, guardrails...)
+	return a
+}
+
+// WithHistory returns the agent with a strategy keeping its conversation
+// within the model's context window.
+func (a *Agent[Context]) WithHistory(strategy HistoryStrategy) *Agent[Context] {
+	a.History = strategy
+	return a
+}
+
+// WithApprovalPolicy returns the agent with a policy deciding which of its
+// tool calls wait for approval.
+func (a *Agent[Context]) WithApprovalPolicy(policy ApprovalPolicy[Context]) *Agent[Context] {
+	a.ApprovalPolicy = policy
+	return a
+}
@@ -0,0 +1,24 @@
+package types
+
+import "github.com/logkn/agents-go/internal/tools"
+
+// ApprovalPolicy decides whether a tool call must be approved before it
+// runs.
+type ApprovalPolicy[Context any] func(ctx *Context, tool tools.Tool[Context], call ToolCall) bool
+
+// RequireApprovalForMarked is the default policy: calls wait for approval
+// when their tool is marked NeedsApproval.
+func RequireApprovalForMarked[Context any](_ *Context, tool tools.Tool[Context], _ ToolCall) bool {
+	return tool.NeedsApproval
+}
+
+// RequireApprovalForAll holds every tool call for approval.
+func RequireApprovalForAll[Context any](*Context, tools.Tool[Context], ToolCall) bool {
+	return true
+}
+
+// RequireApprovalForNone lets every tool call run unattended, including
+// calls to tools marked NeedsApproval.
+func RequireApprovalForNone[Context any](*Context, tools.Tool[Context], ToolCall) bool {
+	return false
+}
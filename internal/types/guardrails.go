@@ -0,0 +1,36 @@
+package types
+
+import "context"
+
+// GuardrailResult is the verdict of a guardrail on a piece of text.
+type GuardrailResult struct {
+	// Tripped stops the run when set.
+	Tripped bool
+	// Reason explains why the guardrail tripped.
+	Reason string
+}
+
+// Guardrail checks the text flowing into or out of an agent. Input guardrails
+// see the user input before the first LLM call completes, output guardrails
+// see the agent's final answer.
+type Guardrail[Context any] struct {
+	// Name identifies the guardrail in errors and logs.
+	Name string
+	// Check inspects text. An error fails the run without tripping the
+	// guardrail.
+	Check func(runCtx context.Context, ctx *Context, text string) (GuardrailResult, error)
+}
+
+// NewGuardrail builds a guardrail from a plain predicate, which trips the
+// guardrail with reason whenever it returns true.
+func NewGuardrail[Context any](name, reason string, tripped func(ctx *Context, text string) bool) Guardrail[Context] {
+	return Guardrail[Context]{
+		Name: name,
+		Check: func(_ context.Context, ctx *Context, text string) (GuardrailResult, error) {
+			if tripped(ctx, text) {
+				return GuardrailResult{Tripped: true, Reason: reason}, nil
+			}
+			return GuardrailResult{}, nil
+		},
+	}
+}
@@ -1,25 +1,85 @@
 package types
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
 	"strings"
 
+	"github.com/logkn/agents-go/internal/tools"
+	"github.com/logkn/agents-go/internal/utils"
 	"github.com/stoewer/go-strcase"
 )
 
+// HandoffPromptMode decides how the prompt given with a handoff reaches the
+// receiving agent.
+type HandoffPromptMode int
+
+const (
+	// PromptAsUserMessage passes the prompt as a user message.
+	PromptAsUserMessage HandoffPromptMode = iota
+	// PromptAsSystemNote passes the prompt as a system message, so the
+	// receiving agent does not mistake it for something the user said.
+	PromptAsSystemNote
+)
+
+// HandoffPrompt is the input of handoffs without an InputType.
+type HandoffPrompt struct {
+	// Prompt tells the receiving agent what to do.
+	Prompt string `json:"prompt"`
+}
+
 type Handoff[Context any] struct {
 	Agent           *Agent[Context]
 	ToolName        string
 	ToolDescription string
+	// InputType is a struct describing the arguments the model passes with
+	// the handoff, its schema derived like a tool's. Without it the model
+	// passes a HandoffPrompt. The receiving agent is given the prompt of a
+	// HandoffPrompt, or the arguments themselves for other input types.
+	InputType any
+	// OnHandoff is called with the decoded input, a value of InputType,
+	// before the transfer, e.g. to record it in the Context. An error
+	// cancels the handoff and is reported to the model.
+	OnHandoff func(ctx *Context, input any) error
+	// InputFilter decides which part of the conversation the receiving agent
+	// sees; nil shows it everything. The run's transcript is not affected.
+	InputFilter HistoryStrategy
+	// PromptMode decides how the handoff prompt is passed on.
+	PromptMode HandoffPromptMode
+}
+
+// HandoffReturn is the input of the tool returning control to the agent
+// that handed off.
+type HandoffReturn struct {
+	// Summary tells the calling agent what was done.
+	Summary string `json:"summary"`
+}
+
+// ReturnHandoff is the handoff offered to an agent reached by handoff, named
+// return_to_{caller_name}, to give control back to caller.
+func ReturnHandoff[Context any](caller *Agent[Context]) Handoff[Context] {
+	return Handoff[Context]{
+		Agent:           caller,
+		ToolName:        "return_to_" + snakeName(caller.Name),
+		ToolDescription: "Return control to the " + caller.Name + " agent, which handed the conversation to you, once your part is done.",
+		InputType:       HandoffReturn{},
+	}
+}
+
+// snakeName turns an agent name into a tool name suffix.
+func snakeName(name string) string {
+	return strcase.SnakeCase(strings.ReplaceAll(name, " ", "_"))
 }
 
 func (h Handoff[Context]) defaultName() string {
 	// "transfer_to_{agent_name}"
-	snakecaseName := strings.ReplaceAll(h.Agent.Name, " ", "_")
-	snakecaseName = strcase.SnakeCase(snakecaseName)
-	return "transfer_to_" + snakecaseName
+	return "transfer_to_" + snakeName(h.Agent.Name)
 }
 
-func (h Handoff[Context]) fullname() string {
+// CompleteName returns the name of the handoff's tool: ToolName if set,
+// otherwise derived from the agent's name.
+func (h Handoff[Context]) CompleteName() string {
 	if h.ToolName != "" {
 		return h.ToolName
 	}
@@ -37,6 +97,52 @@ func (h Handoff[Context]) description() string {
 	return h.defaultDescription()
 }
 
+func (h Handoff[Context]) inputType() any {
+	if h.InputType != nil {
+		return h.InputType
+	}
+	return HandoffPrompt{}
+}
+
+// Definition describes the handoff's tool for an LLM provider. It fails if
+// no schema can be created for the handoff's input type.
+func (h Handoff[Context]) Definition() (tools.Definition, error) {
+	schema, err := utils.CreateSchema(h.inputType())
+	if err != nil {
+		return tools.Definition{}, fmt.Errorf("handoff %s: invalid input type: %w", h.CompleteName(), err)
+	}
+	return tools.Definition{
+		Name:        h.CompleteName(),
+		Description: h.description(),
+		Parameters:  schema,
+	}, nil
+}
+
+// DecodeInput parses the arguments of a call to the handoff's tool into a
+// value of its input type.
+func (h Handoff[Context]) DecodeInput(args string) (any, error) {
+	if strings.TrimSpace(args) == "" {
+		args = "{}"
+	}
+	input := utils.NewInstance(h.inputType())
+	if err := json.Unmarshal([]byte(args), input); err != nil {
+		return nil, err
+	}
+	return reflect.ValueOf(input).Elem().Interface(), nil
+}
+
+// OnHandoffInput adapts a callback taking the handoff's input as a T, the
+// type given as InputType, for use as OnHandoff.
+func OnHandoffInput[Context, T any](fn func(ctx *Context, input T) error) func(ctx *Context, input any) error {
+	return func(ctx *Context, input any) error {
+		typed, ok := input.(T)
+		if !ok {
+			return fmt.Errorf("handoff input is a %T, not a %T", input, *new(T))
+		}
+		return fn(ctx, typed)
+	}
+}
+
 type handoffToolArgs[Context any] struct{}
 
 func (h handoffToolArgs[Context]) Run(ctx *Context) any {
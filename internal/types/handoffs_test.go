@@ -0,0 +1,53 @@
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/logkn/agents-go/internal/utils"
+)
+
+type escalation struct {
+	// Reason explains why the case is escalated.
+	Reason   string `json:"reason"`
+	Priority int    `json:"priority"`
+}
+
+func TestHandoffInput(t *testing.T) {
+	expert := NewAgent[struct{}]("expert", ModelConfig{})
+
+	plain := Handoff[struct{}]{Agent: expert}
+	definition, err := plain.Definition()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	properties, _ := utils.InlineRootRef(definition.Parameters)["properties"].(map[string]any)
+	if _, ok := properties["prompt"]; !ok {
+		t.Fatalf("expected a prompt parameter, got %v", definition.Parameters)
+	}
+	input, err := plain.DecodeInput(`{"prompt":"help"}`)
+	if err != nil || input != (HandoffPrompt{Prompt: "help"}) {
+		t.Fatalf("unexpected input %#v, %v", input, err)
+	}
+
+	typed := Handoff[struct{}]{Agent: expert, InputType: escalation{}}
+	if definition, err = typed.Definition(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	properties, _ = utils.InlineRootRef(definition.Parameters)["properties"].(map[string]any)
+	if _, ok := properties["priority"]; !ok {
+		t.Fatalf("expected the schema of the input type, got %v", definition.Parameters)
+	}
+	input, err = typed.DecodeInput(`{"reason":"refund","priority":2}`)
+	if err != nil || input != (escalation{Reason: "refund", Priority: 2}) {
+		t.Fatalf("unexpected input %#v, %v", input, err)
+	}
+	if _, err := typed.DecodeInput(`{"priority":"high"}`); err == nil {
+		t.Fatalf("expected an error for malformed arguments")
+	}
+
+	onHandoff := OnHandoffInput(func(_ *struct{}, input escalation) error { return nil })
+	if err := onHandoff(nil, HandoffPrompt{}); err == nil || !strings.Contains(err.Error(), "escalation") {
+		t.Fatalf("expected a type mismatch error, got %v", err)
+	}
+}
@@ -0,0 +1,17 @@
+package types
+
+import "context"
+
+// HistoryStrategy decides which part of a conversation is sent to the model,
+// keeping long conversations within the context window. It is applied before
+// every LLM call and must not modify the messages it is given.
+type HistoryStrategy interface {
+	Compact(ctx context.Context, messages []Message) ([]Message, error)
+}
+
+// HistoryFunc adapts a plain function to a HistoryStrategy.
+type HistoryFunc func(ctx context.Context, messages []Message) ([]Message, error)
+
+func (f HistoryFunc) Compact(ctx context.Context, messages []Message) ([]Message, error) {
+	return f(ctx, messages)
+}
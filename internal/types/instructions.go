@@ -3,15 +3,42 @@ package types
 import (
 	"bytes"
 	"errors"
-	"html/template"
+	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"text/template"
+	"time"
 )
 
+// maxIncludeDepth bounds how deeply partial templates can include each other.
+const maxIncludeDepth = 16
+
+// AgentInstructions is an agent's system prompt, rendered before every LLM
+// call. Exactly one of OfString, OfFile and OfFunc should be set.
+//
+// Strings and files are text/template templates executed with the run's
+// Context as dot. Besides Funcs, templates can use these helpers:
+//
+//	now       the current time: {{ now.Format "2006-01-02" }}
+//	env       an environment variable: {{ env "USER" }}
+//	readFile  a file's contents: {{ readFile "notes.md" }}
+//	include   a partial template, rendered with the given data or the
+//	          Context: {{ include "partials/tools.md" . }}
+//	join      a slice's elements, separated: {{ join ", " .Names }}
+//
+// Relative paths are resolved against the directory of the file being
+// rendered, or the working directory for OfString.
 type AgentInstructions[Context any] struct {
 	OfString string
 	OfFile   string
+	// OfFunc builds the instructions itself, without templating.
+	OfFunc func(ctx *Context, agent *Agent[Context]) (string, error)
+	// Funcs adds helpers available to templates, replacing built-in ones of
+	// the same name.
+	Funcs template.FuncMap
 }
 
 func StringInstructions[Context any](s string) AgentInstructions[Context] {
@@ -22,57 +49,113 @@ func FileInstructions[Context any](file string) AgentInstructions[Context] {
 	return AgentInstructions[Context]{OfFile: file}
 }
 
-func (ins AgentInstructions[Context]) getContent() (string, error) {
-	// Case: OfString
-	if ins.OfString != "" {
-		return ins.OfString, nil
+func FuncInstructions[Context any](fn func(ctx *Context, agent *Agent[Context]) (string, error)) AgentInstructions[Context] {
+	return AgentInstructions[Context]{OfFunc: fn}
+}
+
+// expandHome expands a leading ~/ to the user's home directory.
+func expandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
 	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, path[2:]), nil
+}
 
-	// Case: Neither
-	if ins.OfFile == "" {
-		return "", errors.New("no instruction provided for agent")
+// resolvePath returns path relative to dir, after expanding ~/.
+func resolvePath(dir, path string) (string, error) {
+	path, err := expandHome(path)
+	if err != nil || filepath.IsAbs(path) {
+		return path, err
 	}
+	return filepath.Join(dir, path), nil
+}
 
-	// Case: OfFile
-	// Expand user home directory if path starts with ~
-	filePath := ins.OfFile
-	if strings.HasPrefix(filePath, "~/") {
-		homeDir, err := os.UserHomeDir()
+// Render returns the instructions agent runs with for ctx.
+func (ins AgentInstructions[Context]) Render(ctx *Context, agent *Agent[Context]) (string, error) {
+	switch {
+	case ins.OfFunc != nil:
+		return ins.OfFunc(ctx, agent)
+	case ins.OfString != "":
+		return ins.execute("instructions", ins.OfString, "", ctx, 0)
+	case ins.OfFile != "":
+		path, err := expandHome(ins.OfFile)
 		if err != nil {
 			return "", err
 		}
-		filePath = filepath.Join(homeDir, filePath[2:])
-	}
-
-	// Read file contents
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return "", err
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return ins.execute(filepath.Base(path), string(content), filepath.Dir(path), ctx, 0)
 	}
-	return string(content), nil
+	return "", errors.New("no instruction provided for agent")
 }
 
+// ToString renders instructions that do not use OfFunc for ctx.
 func (ins AgentInstructions[Context]) ToString(ctx *Context) (string, error) {
-	content, err := ins.getContent()
-	if err != nil {
-		return "", err
-	}
+	return ins.Render(ctx, nil)
+}
 
-	templ, err := template.New("instructions").Option("missingkey=error").Parse(content)
-	if err != nil {
-		return "", err
+// execute renders content as the template called name, with data as dot and
+// relative paths resolved against dir. depth counts the partials it is
+// included from.
+func (ins AgentInstructions[Context]) execute(name, content, dir string, data any, depth int) (string, error) {
+	funcs := template.FuncMap{
+		"now":  time.Now,
+		"env":  os.Getenv,
+		"join": join,
+		"readFile": func(path string) (string, error) {
+			path, err := resolvePath(dir, path)
+			if err != nil {
+				return "", err
+			}
+			content, err := os.ReadFile(path)
+			return string(content), err
+		},
+		"include": func(path string, partialData ...any) (string, error) {
+			if depth >= maxIncludeDepth {
+				return "", fmt.Errorf("include %s: partials nested more than %d deep", path, maxIncludeDepth)
+			}
+			path, err := resolvePath(dir, path)
+			if err != nil {
+				return "", err
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+			if len(partialData) == 0 {
+				partialData = []any{data}
+			}
+			return ins.execute(filepath.Base(path), string(content), filepath.Dir(path), partialData[0], depth+1)
+		},
 	}
+	maps.Copy(funcs, ins.Funcs)
 
-	// get the value out of the context
+	templ, err := template.New(name).Option("missingkey=error").Funcs(funcs).Parse(content)
 	if err != nil {
 		return "", err
 	}
-
-	// make a buffer to hold the output
 	var buffer bytes.Buffer
-	err = templ.Execute(&buffer, ctx)
-	if err != nil {
+	if err := templ.Execute(&buffer, data); err != nil {
 		return "", err
 	}
 	return buffer.String(), nil
 }
+
+// join formats the elements of a slice or array and joins them with sep.
+func join(sep string, elems any) (string, error) {
+	value := reflect.ValueOf(elems)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: expected a slice, got %T", elems)
+	}
+	parts := make([]string, value.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(value.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
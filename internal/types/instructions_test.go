@@ -0,0 +1,76 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+type promptContext struct {
+	User  string
+	Tools []string
+}
+
+func TestInstructionsRenderPlainText(t *testing.T) {
+	ins := StringInstructions[promptContext](`<example_behavior>Say "hi" to {{ .User }} & use {{ join ", " .Tools }}.</example_behavior>`)
+	got, err := ins.ToString(&promptContext{User: "<bob>", Tools: []string{"grep", "edit"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `<example_behavior>Say "hi" to <bob> & use grep, edit.</example_behavior>`
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestInstructionsFuncs(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "partials"), 0o755)
+	os.WriteFile(filepath.Join(dir, "partials", "user.md"), []byte(`User: {{ .User }}. {{ include "tools.md" .Tools }}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "partials", "tools.md"), []byte(`Tools: {{ join "/" . }}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "prompt.md"), []byte(`{{ include "partials/user.md" }} Shell: {{ env "INSTRUCTIONS_TEST_SHELL" }}. Year: {{ now.Year }}. {{ shout "done" }}`), 0o644)
+	t.Setenv("INSTRUCTIONS_TEST_SHELL", "zsh")
+
+	ins := FileInstructions[promptContext](filepath.Join(dir, "prompt.md"))
+	ins.Funcs = template.FuncMap{"shout": strings.ToUpper}
+	got, err := ins.ToString(&promptContext{User: "ada", Tools: []string{"grep", "edit"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(got, "User: ada. Tools: grep/edit Shell: zsh. Year: 2") || !strings.HasSuffix(got, "DONE") {
+		t.Fatalf("unexpected instructions %q", got)
+	}
+
+	// partials including themselves fail instead of looping forever
+	os.WriteFile(filepath.Join(dir, "loop.md"), []byte(`{{ include "loop.md" }}`), 0o644)
+	if _, err := FileInstructions[promptContext](filepath.Join(dir, "loop.md")).ToString(&promptContext{}); err == nil {
+		t.Fatalf("expected an error for recursive partials")
+	}
+}
+
+func TestInstructionsFunc(t *testing.T) {
+	agent := NewAgent[promptContext]("helper", ModelConfig{}).
+		WithInstructionsFunc(func(ctx *promptContext, agent *Agent[promptContext]) (string, error) {
+			return "You are " + agent.Name + ", helping " + ctx.User + ".", nil
+		})
+	got, err := agent.Instructions.Render(&promptContext{User: "ada"}, agent)
+	if err != nil || got != "You are helper, helping ada." {
+		t.Fatalf("unexpected instructions %q (%v)", got, err)
+	}
+}
+
+func TestInstructionsErrors(t *testing.T) {
+	tests := []AgentInstructions[promptContext]{
+		{},
+		StringInstructions[promptContext]("{{ .Missing }}"),
+		StringInstructions[promptContext]("{{ .User "),
+		FileInstructions[promptContext](filepath.Join(t.TempDir(), "missing.md")),
+	}
+	for _, ins := range tests {
+		if got, err := ins.ToString(&promptContext{}); err == nil {
+			t.Fatalf("expected an error for %+v, got %q", ins, got)
+		}
+	}
+}
@@ -40,6 +40,10 @@ type Message struct {
 	Name      string     `json:"name,omitempty"`
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 	ID        string     `json:"id,omitempty"` // for tool messages
+	// Reasoning is thinking the provider returned separately from Content.
+	Reasoning string `json:"reasoning,omitempty"`
+	// ReasoningSignature lets providers verify replayed reasoning.
+	ReasoningSignature string `json:"reasoning_signature,omitempty"`
 }
 
 // ToOpenAI converts the message into the OpenAI SDK representation.
@@ -1,12 +1,95 @@
 package types
 
+import (
+	"os"
+	"time"
+)
+
+// Tool choices understood by every provider. Any other ToolChoice value names
+// the tool the model must call.
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+)
+
 // ModelConfig contains configuration details for an LLM model.
 // Model is the identifier of the model to use and BaseUrl is an optional
-// override for the API base URL.
+// override for the API base URL. Provider selects the backend used to serve
+// the model; when nil the OpenAI Chat Completions API is used.
 type ModelConfig struct {
-	Model       string
-	BaseURL     string
-	Temperature float32
+	Model    string
+	BaseURL  string
+	Provider Provider
+	// Temperature sets the sampling temperature; nil leaves the provider's
+	// default.
+	Temperature *float32
+	// ThinkingBudget enables extended thinking with the given token budget
+	// on providers that support it; zero leaves thinking off.
+	ThinkingBudget int
+
+	// MaxTokens caps the tokens generated per turn.
+	MaxTokens int
+	// TopP enables nucleus sampling; nil leaves the provider's default.
+	TopP *float32
+	// Seed requests deterministic sampling where supported.
+	Seed *int64
+	// Stop lists sequences that end generation.
+	Stop []string
+	// ToolChoice is one of the ToolChoice constants or the name of the tool
+	// the model must call.
+	ToolChoice string
+	// ParallelToolCalls allows or forbids several tool calls per turn; nil
+	// leaves the provider's default.
+	ParallelToolCalls *bool
+	// ReasoningEffort is "low", "medium" or "high" on reasoning models.
+	ReasoningEffort string
+	// SendReasoning replays the reasoning of earlier assistant turns to the
+	// model. Signed reasoning, which some providers require alongside tool
+	// calls, is replayed regardless.
+	SendReasoning bool
+
+	// APIKey authenticates requests. When empty the key is read from the
+	// APIKeyEnv environment variable, then from the provider's usual one.
+	APIKey    string
+	APIKeyEnv string
+	// ExtraHeaders are added to every request.
+	ExtraHeaders map[string]string
+	// RequestTimeout caps each LLM request, including streaming its
+	// response. Timed out requests are retried like transient failures.
+	RequestTimeout time.Duration
+
+	// Think toggles thinking on models served by Ollama; nil leaves the
+	// model's default.
+	Think *bool
+	// KeepAlive controls how long Ollama keeps the model loaded after a
+	// request. Zero leaves the server default and negative keeps it loaded
+	// indefinitely.
+	KeepAlive time.Duration
+	// NumCtx sets the context window Ollama allocates for the model.
+	NumCtx int
+	// Options are passed through as Ollama model options, e.g. num_predict.
+	Options map[string]any
+
+	// Retry controls how failed requests to the model are retried.
+	Retry RetryPolicy
+	// Pricing overrides the pricing table entry used to estimate costs.
+	Pricing *ModelPrice
+}
+
+// ResolveAPIKey returns the API key configured for the model, falling back to
+// the defaultEnv environment variable.
+func (config ModelConfig) ResolveAPIKey(defaultEnv string) string {
+	if config.APIKey != "" {
+		return config.APIKey
+	}
+	if config.APIKeyEnv != "" {
+		return os.Getenv(config.APIKeyEnv)
+	}
+	if defaultEnv != "" {
+		return os.Getenv(defaultEnv)
+	}
+	return ""
 }
 
 type ModelOption interface {
@@ -23,9 +106,10 @@ func (config *ModelConfig) Apply(opts ...ModelOption) error {
 }
 
 func DefaultModel(model string) ModelConfig {
+	temperature := float32(0.6)
 	return ModelConfig{
 		Model: model,
 		// BaseURL:     "http://localhost:11434/v1",
-		Temperature: 0.6,
+		Temperature: &temperature,
 	}
 }
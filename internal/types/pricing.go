@@ -0,0 +1,80 @@
+package types
+
+import (
+	"strings"
+	"sync"
+)
+
+// ModelPrice is what a model costs in USD per million tokens.
+type ModelPrice struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// Cost returns the price in USD of the given usage.
+func (p ModelPrice) Cost(usage Usage) float64 {
+	return (float64(usage.InputTokens)*p.InputPerMillion + float64(usage.OutputTokens)*p.OutputPerMillion) / 1e6
+}
+
+var (
+	pricesMu sync.RWMutex
+	// prices lists public list prices. Models missing from the table, such as
+	// local ones, are priced at zero.
+	prices = map[string]ModelPrice{
+		"gpt-5":             {InputPerMillion: 1.25, OutputPerMillion: 10},
+		"gpt-5-mini":        {InputPerMillion: 0.25, OutputPerMillion: 2},
+		"gpt-5-nano":        {InputPerMillion: 0.05, OutputPerMillion: 0.40},
+		"gpt-4.1":           {InputPerMillion: 2, OutputPerMillion: 8},
+		"gpt-4.1-mini":      {InputPerMillion: 0.40, OutputPerMillion: 1.60},
+		"gpt-4.1-nano":      {InputPerMillion: 0.10, OutputPerMillion: 0.40},
+		"gpt-4o":            {InputPerMillion: 2.50, OutputPerMillion: 10},
+		"gpt-4o-mini":       {InputPerMillion: 0.15, OutputPerMillion: 0.60},
+		"o3":                {InputPerMillion: 2, OutputPerMillion: 8},
+		"o3-mini":           {InputPerMillion: 1.10, OutputPerMillion: 4.40},
+		"o4-mini":           {InputPerMillion: 1.10, OutputPerMillion: 4.40},
+		"claude-opus-4":     {InputPerMillion: 15, OutputPerMillion: 75},
+		"claude-opus-4-1":   {InputPerMillion: 15, OutputPerMillion: 75},
+		"claude-sonnet-4":   {InputPerMillion: 3, OutputPerMillion: 15},
+		"claude-sonnet-4-5": {InputPerMillion: 3, OutputPerMillion: 15},
+		"claude-3-7-sonnet": {InputPerMillion: 3, OutputPerMillion: 15},
+		"claude-haiku-4-5":  {InputPerMillion: 1, OutputPerMillion: 5},
+		"claude-3-5-haiku":  {InputPerMillion: 0.80, OutputPerMillion: 4},
+	}
+)
+
+// SetModelPrice adds or replaces the price of a model in the pricing table.
+func SetModelPrice(model string, price ModelPrice) {
+	pricesMu.Lock()
+	defer pricesMu.Unlock()
+	prices[model] = price
+}
+
+// PriceFor looks up a model in the pricing table. Dated or tagged variants
+// such as "claude-sonnet-4-5-20250929" match their base model.
+func PriceFor(model string) (ModelPrice, bool) {
+	pricesMu.RLock()
+	defer pricesMu.RUnlock()
+
+	best, found := "", false
+	for name := range prices {
+		if len(name) <= len(best) || !strings.HasPrefix(model, name) {
+			continue
+		}
+		// only match whole name segments, so "o3" does not price "o3x"
+		if rest := model[len(name):]; rest != "" && rest[0] != '-' && rest[0] != ':' {
+			continue
+		}
+		best, found = name, true
+	}
+	return prices[best], found
+}
+
+// Price returns the price of the model: its Pricing override if set, the
+// pricing table entry otherwise, or zero for unknown models.
+func (config ModelConfig) Price() ModelPrice {
+	if config.Pricing != nil {
+		return *config.Pricing
+	}
+	price, _ := PriceFor(config.Model)
+	return price
+}
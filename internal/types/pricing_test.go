@@ -0,0 +1,25 @@
+package types
+
+import "testing"
+
+func TestModelPrice(t *testing.T) {
+	price, ok := PriceFor("gpt-4o-mini-2024-07-18")
+	if !ok || price != prices["gpt-4o-mini"] {
+		t.Fatalf("expected the gpt-4o-mini price, got %+v", price)
+	}
+	if _, ok := PriceFor("o3x"); ok {
+		t.Fatalf("partial names should not match")
+	}
+
+	usage := Usage{InputTokens: 1_000_000, OutputTokens: 500_000}
+	if cost := (ModelConfig{Model: "claude-sonnet-4-5-20250929"}).Price().Cost(usage); cost != 10.5 {
+		t.Fatalf("unexpected cost %v", cost)
+	}
+	if cost := (ModelConfig{Model: "qwen3:30b-a3b"}).Price().Cost(usage); cost != 0 {
+		t.Fatalf("local models should be free, got %v", cost)
+	}
+	override := ModelConfig{Model: "gpt-4o", Pricing: &ModelPrice{}}
+	if cost := override.Price().Cost(usage); cost != 0 {
+		t.Fatalf("override should win, got %v", cost)
+	}
+}
@@ -0,0 +1,173 @@
+package types
+
+import (
+	"context"
+	"strings"
+
+	"github.com/logkn/agents-go/internal/tools"
+)
+
+// Provider streams completions from an LLM backend. Implementations translate
+// a CompletionRequest into their own wire format and report the response back
+// as a sequence of CompletionChunks.
+type Provider interface {
+	Stream(ctx context.Context, req CompletionRequest) (CompletionStream, error)
+}
+
+// ModelValidator is implemented by providers that can check, before a run
+// starts, that the configured model is available. Runs nested in another, and
+// resumed runs, are not checked again.
+type ModelValidator interface {
+	ValidateModel(ctx context.Context, model ModelConfig) error
+}
+
+// ResponseFormatter is implemented by providers that can natively constrain
+// an answer to a JSON schema. Structured output for other providers is
+// requested through a synthetic tool instead.
+type ResponseFormatter interface {
+	SupportsResponseFormat() bool
+}
+
+// CompletionRequest contains everything a Provider needs to produce a single
+// assistant turn. Messages already include the system prompt.
+type CompletionRequest struct {
+	Model    ModelConfig
+	Messages []Message
+	Tools    []tools.Definition
+	// ResponseFormat, when set, requires the answer to be JSON matching its
+	// schema. It is only sent to providers implementing ResponseFormatter.
+	ResponseFormat *ResponseFormat
+}
+
+// ResponseFormat describes the JSON document the model must answer with.
+type ResponseFormat struct {
+	Name   string
+	Schema map[string]any
+}
+
+// CompletionStream yields the chunks of a streamed completion. Next advances
+// to the following chunk and returns false once the stream is exhausted or has
+// failed, in which case Err reports the failure.
+type CompletionStream interface {
+	Next() bool
+	Current() CompletionChunk
+	Err() error
+	Close() error
+}
+
+// CompletionChunk is an incremental piece of an assistant turn.
+type CompletionChunk struct {
+	// Content is the next fragment of the assistant's answer.
+	Content string
+	// Reasoning is the next fragment of the model's thinking, for providers
+	// that stream it separately from the answer.
+	Reasoning string
+	// ReasoningSignature is an opaque token some providers attach to their
+	// thinking so it can be replayed on later turns.
+	ReasoningSignature string
+	// ToolCalls carries fragments of the tool calls being generated.
+	ToolCalls []ToolCallDelta
+	// Refusal is set when the model declines to answer.
+	Refusal string
+	// FinishReason is reported by the final chunk of a turn.
+	FinishReason string
+	// Usage is reported once the provider knows the token counts.
+	Usage *Usage
+}
+
+// ToolCallDelta is a fragment of a tool call. Fragments sharing an Index
+// belong to the same call; ID and Name are usually only sent on the first one.
+type ToolCallDelta struct {
+	Index int
+	ID    string
+	Name  string
+	Args  string
+}
+
+// Usage reports the tokens consumed by a completion.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+	TotalTokens  int
+}
+
+// Add returns the sum of both usages.
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		InputTokens:  u.InputTokens + other.InputTokens,
+		OutputTokens: u.OutputTokens + other.OutputTokens,
+		TotalTokens:  u.TotalTokens + other.TotalTokens,
+	}
+}
+
+// CompletionAccumulator assembles streamed chunks into a complete assistant
+// message.
+type CompletionAccumulator struct {
+	content      strings.Builder
+	reasoning    strings.Builder
+	signature    strings.Builder
+	refusal      strings.Builder
+	toolCalls    []ToolCall
+	chunks       int
+	FinishReason string
+	Usage        *Usage
+}
+
+// AddChunk merges the chunk into the accumulated response.
+func (acc *CompletionAccumulator) AddChunk(chunk CompletionChunk) {
+	acc.chunks++
+	acc.content.WriteString(chunk.Content)
+	acc.reasoning.WriteString(chunk.Reasoning)
+	acc.signature.WriteString(chunk.ReasoningSignature)
+	acc.refusal.WriteString(chunk.Refusal)
+
+	for _, delta := range chunk.ToolCalls {
+		for len(acc.toolCalls) <= delta.Index {
+			acc.toolCalls = append(acc.toolCalls, ToolCall{})
+		}
+		call := &acc.toolCalls[delta.Index]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Name != "" {
+			call.Name = delta.Name
+		}
+		call.Args += delta.Args
+	}
+
+	if chunk.FinishReason != "" {
+		acc.FinishReason = chunk.FinishReason
+	}
+	if chunk.Usage != nil {
+		acc.Usage = chunk.Usage
+	}
+}
+
+// Empty reports whether no chunks have been received.
+func (acc *CompletionAccumulator) Empty() bool {
+	return acc.chunks == 0
+}
+
+// Refusal returns the accumulated refusal, if any.
+func (acc *CompletionAccumulator) Refusal() string {
+	return acc.refusal.String()
+}
+
+// Message returns the accumulated assistant message attributed to name.
+func (acc *CompletionAccumulator) Message(name string) Message {
+	toolCalls := make([]ToolCall, 0, len(acc.toolCalls))
+	for _, call := range acc.toolCalls {
+		// providers may skip indices; drop the holes
+		if call.Name == "" {
+			continue
+		}
+		if call.Args == "" {
+			call.Args = "{}"
+		}
+		toolCalls = append(toolCalls, call)
+	}
+	msg := NewAssistantMessage(acc.content.String(), name, toolCalls)
+	msg.Reasoning = acc.reasoning.String()
+	msg.ReasoningSignature = acc.signature.String()
+	return msg
+}
@@ -0,0 +1,32 @@
+package types
+
+import "testing"
+
+func TestCompletionAccumulator(t *testing.T) {
+	acc := CompletionAccumulator{}
+	if !acc.Empty() {
+		t.Fatalf("new accumulator should be empty")
+	}
+	acc.AddChunk(CompletionChunk{Content: "Hel"})
+	acc.AddChunk(CompletionChunk{Content: "lo", ToolCalls: []ToolCallDelta{{Index: 1, ID: "b", Name: "second"}}})
+	acc.AddChunk(CompletionChunk{ToolCalls: []ToolCallDelta{{Index: 0, ID: "a", Name: "first", Args: `{"x":`}}})
+	acc.AddChunk(CompletionChunk{ToolCalls: []ToolCallDelta{{Index: 0, Args: `1}`}}})
+	acc.AddChunk(CompletionChunk{FinishReason: "tool_calls", Usage: &Usage{TotalTokens: 7}})
+
+	msg := acc.Message("bot")
+	if msg.Content != "Hello" || msg.Name != "bot" || msg.Role != Assistant {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	if len(msg.ToolCalls) != 2 {
+		t.Fatalf("expected 2 tool calls, got %d", len(msg.ToolCalls))
+	}
+	if msg.ToolCalls[0] != (ToolCall{ID: "a", Name: "first", Args: `{"x":1}`}) {
+		t.Fatalf("unexpected first call %+v", msg.ToolCalls[0])
+	}
+	if msg.ToolCalls[1].Args != "{}" {
+		t.Fatalf("missing arguments should default to an empty object")
+	}
+	if acc.FinishReason != "tool_calls" || acc.Usage.TotalTokens != 7 {
+		t.Fatalf("finish reason or usage not recorded")
+	}
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"maps"
+	"slices"
+)
+
+// AgentRegistry finds agents by name, such as the agent a saved run was
+// using when it stopped.
+type AgentRegistry[Context any] struct {
+	agents map[string]*Agent[Context]
+}
+
+// NewAgentRegistry returns a registry holding agents and every agent they
+// can hand off to.
+func NewAgentRegistry[Context any](agents ...*Agent[Context]) *AgentRegistry[Context] {
+	r := &AgentRegistry[Context]{agents: map[string]*Agent[Context]{}}
+	r.Register(agents...)
+	return r
+}
+
+// Register adds agents and every agent they can hand off to. An agent whose
+// name is already taken is skipped, along with its handoffs.
+func (r *AgentRegistry[Context]) Register(agents ...*Agent[Context]) {
+	for _, agent := range agents {
+		if agent == nil {
+			continue
+		}
+		if _, taken := r.agents[agent.Name]; taken {
+			continue
+		}
+		r.agents[agent.Name] = agent
+		for _, handoff := range agent.Handoffs {
+			r.Register(handoff.Agent)
+		}
+	}
+}
+
+// Lookup returns the agent registered under name.
+func (r *AgentRegistry[Context]) Lookup(name string) (*Agent[Context], bool) {
+	agent, ok := r.agents[name]
+	return agent, ok
+}
+
+// Names returns the names of the registered agents in sorted order.
+func (r *AgentRegistry[Context]) Names() []string {
+	return slices.Sorted(maps.Keys(r.agents))
+}
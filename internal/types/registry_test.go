@@ -0,0 +1,26 @@
+package types
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAgentRegistry(t *testing.T) {
+	billing := NewAgent[struct{}]("billing", ModelConfig{})
+	support := NewAgent[struct{}]("support", ModelConfig{})
+	triage := NewAgent[struct{}]("triage", ModelConfig{}).
+		WithHandoffs([]Handoff[struct{}]{{Agent: billing}, {Agent: support}})
+	// handoff cycles must not loop forever
+	support.WithHandoffs([]Handoff[struct{}]{{Agent: triage}})
+
+	registry := NewAgentRegistry(triage)
+	if names := registry.Names(); !slices.Equal(names, []string{"billing", "support", "triage"}) {
+		t.Fatalf("unexpected agents %v", names)
+	}
+	if agent, ok := registry.Lookup("billing"); !ok || agent != billing {
+		t.Fatalf("expected to find the billing agent")
+	}
+	if _, ok := registry.Lookup("sales"); ok {
+		t.Fatalf("expected no sales agent")
+	}
+}
@@ -0,0 +1,71 @@
+package types
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how failed LLM requests are retried. Zero fields fall
+// back to the defaults of DefaultRetryPolicy; set MaxAttempts to 1 to disable
+// retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts.
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after every attempt.
+	Multiplier float64
+	// Jitter randomizes each delay by up to this fraction of it.
+	Jitter float64
+}
+
+// DefaultRetryPolicy returns the policy used when a model does not configure
+// one.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// withDefaults fills unset fields from DefaultRetryPolicy.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	defaults := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaults.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = defaults.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = defaults.MaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = defaults.Multiplier
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		p.Jitter = defaults.Jitter
+	}
+	return p
+}
+
+// Attempts returns the total number of attempts allowed.
+func (p RetryPolicy) Attempts() int {
+	return p.withDefaults().MaxAttempts
+}
+
+// Backoff returns the delay before the given retry, counting from 1.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	p = p.withDefaults()
+	delay := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(retry-1))
+	delay = math.Min(delay, float64(p.MaxBackoff))
+	// spread retries out so concurrent runs do not hit the API in lockstep
+	delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	return time.Duration(delay)
+}
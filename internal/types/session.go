@@ -0,0 +1,15 @@
+package types
+
+import "context"
+
+// Session stores the conversation of a chat across runs. A run given a
+// session prepends its history to the input and saves the messages it adds
+// once it finishes. Implementations must be safe for concurrent use.
+type Session interface {
+	// Load returns the stored conversation, oldest message first.
+	Load(ctx context.Context) ([]Message, error)
+	// Append adds messages to the end of the conversation.
+	Append(ctx context.Context, messages ...Message) error
+	// Clear removes the whole conversation.
+	Clear(ctx context.Context) error
+}
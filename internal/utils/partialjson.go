@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ParsePartialJSON parses JSON that may have been cut off, as tool call
+// arguments are while they stream. See CompletePartialJSON for how the
+// missing end is filled in.
+func ParsePartialJSON(s string) (any, error) {
+	var value any
+	if err := json.Unmarshal([]byte(CompletePartialJSON(s)), &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// partialFrame is an array or object left open in partial JSON.
+type partialFrame struct {
+	closer byte
+	// expectKey is set while an object waits for its next key.
+	expectKey bool
+}
+
+// CompletePartialJSON closes the unterminated strings, arrays and objects of
+// partial JSON. Trailing fragments that cannot be completed, such as a half
+// written key or literal, are dropped; a number at the very end is kept as
+// far as it parses, so it may still grow. Input that is already valid is
+// returned unchanged.
+func CompletePartialJSON(s string) string {
+	var (
+		stack []partialFrame
+		// cut is the end of the longest prefix known to be complete, and
+		// cutClosers closes the containers open at that point.
+		cut        int
+		cutClosers string
+		inString   bool
+		isKey      bool
+		// stringSafe is the end of the last whole character of the open
+		// string, so an escape sequence is never split.
+		stringSafe int
+	)
+	markCut := func(pos int) {
+		cut, cutClosers = pos, closersOf(stack)
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch c {
+			case '\\':
+				width := 2
+				if i+1 < len(s) && s[i+1] == 'u' {
+					width = 6
+				}
+				if i+width > len(s) {
+					i = len(s)
+					continue
+				}
+				i += width - 1
+				stringSafe = i + 1
+			case '"':
+				inString = false
+				if !isKey {
+					markCut(i + 1)
+				}
+			default:
+				stringSafe = i + 1
+			}
+			continue
+		}
+
+		switch c {
+		case ' ', '\t', '\n', '\r':
+		case '{':
+			stack = append(stack, partialFrame{closer: '}', expectKey: true})
+			markCut(i + 1)
+		case '[':
+			stack = append(stack, partialFrame{closer: ']'})
+			markCut(i + 1)
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			markCut(i + 1)
+		case ',':
+			if len(stack) > 0 && stack[len(stack)-1].closer == '}' {
+				stack[len(stack)-1].expectKey = true
+			}
+		case ':':
+			if len(stack) > 0 {
+				stack[len(stack)-1].expectKey = false
+			}
+		case '"':
+			inString = true
+			isKey = len(stack) > 0 && stack[len(stack)-1].expectKey
+			stringSafe = i + 1
+		default:
+			// a literal or number runs up to the next delimiter
+			end := i
+			for end < len(s) && !strings.ContainsRune(" \t\n\r,:]}", rune(s[end])) {
+				end++
+			}
+			if end < len(s) {
+				i = end - 1
+				markCut(end)
+				continue
+			}
+			token := s[i:]
+			if c == '-' || (c >= '0' && c <= '9') {
+				token = strings.TrimRight(token, "+-.eE")
+			}
+			if token != "" && json.Valid([]byte(token)) {
+				return s[:i+len(token)] + closersOf(stack)
+			}
+			return s[:cut] + cutClosers
+		}
+	}
+
+	if inString && !isKey {
+		return s[:stringSafe] + `"` + closersOf(stack)
+	}
+	return s[:cut] + cutClosers
+}
+
+// closersOf returns the characters closing the open containers, innermost
+// first.
+func closersOf(stack []partialFrame) string {
+	closers := make([]byte, len(stack))
+	for i, frame := range stack {
+		closers[len(stack)-1-i] = frame.closer
+	}
+	return string(closers)
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompletePartialJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{``, ``},
+		{`{`, `{}`},
+		{`{"file_pa`, `{}`},
+		{`{"file_path"`, `{}`},
+		{`{"file_path": `, `{}`},
+		{`{"file_path": "/tmp/ma`, `{"file_path": "/tmp/ma"}`},
+		{`{"file_path": "/tmp/main.go", `, `{"file_path": "/tmp/main.go"}`},
+		{`{"content": "line\`, `{"content": "line"}`},
+		{`{"content": "tab\t`, `{"content": "tab\t"}`},
+		{`{"content": "\u00`, `{"content": ""}`},
+		{`{"n": 12`, `{"n": 12}`},
+		{`{"n": -1.`, `{"n": -1}`},
+		{`{"n": -`, `{}`},
+		{`{"ok": tr`, `{}`},
+		{`{"ok": true`, `{"ok": true}`},
+		{`{"items": [1, {"a": [`, `{"items": [1, {"a": []}]}`},
+		{`{"items": ["x", "y`, `{"items": ["x", "y"]}`},
+		{`{"a": {"b": 1}}`, `{"a": {"b": 1}}`},
+	}
+	for _, tt := range tests {
+		if got := CompletePartialJSON(tt.in); got != tt.want {
+			t.Errorf("CompletePartialJSON(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParsePartialJSON(t *testing.T) {
+	value, err := ParsePartialJSON(`{"file_path": "main.go", "content": "package ma`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{"file_path": "main.go", "content": "package ma"}
+	if !reflect.DeepEqual(value, want) {
+		t.Fatalf("expected %v got %v", want, value)
+	}
+
+	if _, err := ParsePartialJSON(`not json`); err == nil {
+		t.Fatalf("expected an error for invalid input")
+	}
+}
@@ -68,6 +68,34 @@ func CreateSchema(dataStructure any) (map[string]any, error) {
 	return result, nil
 }
 
+// InlineRootRef returns a copy of schema whose top-level "$ref" is replaced by
+// the definition it points to, for APIs that require the root to describe an
+// object directly. Other definitions are kept so nested references still
+// resolve.
+func InlineRootRef(schema map[string]any) map[string]any {
+	ref, ok := schema["$ref"].(string)
+	if !ok || !strings.HasPrefix(ref, "#/$defs/") {
+		return schema
+	}
+	defs, _ := schema["$defs"].(map[string]any)
+	def, ok := defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+	if !ok {
+		return schema
+	}
+
+	result := make(map[string]any, len(schema)+len(def))
+	for key, value := range schema {
+		if key == "$ref" || key == "$id" || key == "$schema" {
+			continue
+		}
+		result[key] = value
+	}
+	for key, value := range def {
+		result[key] = value
+	}
+	return result
+}
+
 // addGoCommentsAuto attempts to locate the source of the provided type and add
 // Go comments to the JSON schema reflector so they appear in the schema output.
 func addGoCommentsAuto(r *jsonschema.Reflector, t reflect.Type) error {
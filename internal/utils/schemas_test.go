@@ -23,3 +23,23 @@ func TestCreateSchema(t *testing.T) {
 		t.Fatalf("expected object type")
 	}
 }
+
+func TestInlineRootRef(t *testing.T) {
+	schema, err := CreateSchema(sampleStruct{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	inlined := InlineRootRef(schema)
+	if inlined["type"] != "object" {
+		t.Fatalf("expected object type at the root, got %v", inlined["type"])
+	}
+	if _, ok := inlined["$ref"]; ok {
+		t.Fatalf("root $ref should be removed")
+	}
+	if _, ok := inlined["properties"].(map[string]any)["field"]; !ok {
+		t.Fatalf("expected field property at the root")
+	}
+	if _, ok := schema["$ref"]; !ok {
+		t.Fatalf("original schema should be left untouched")
+	}
+}
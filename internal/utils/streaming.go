@@ -178,3 +178,79 @@ func DetectThinking(stream chan string) chan Token {
 	}()
 	return output
 }
+
+// ThinkSplitter separates the <think> sections that models such as qwen
+// write inline from the rest of a streamed answer. Tags split across chunks
+// are recognized; text that might start a tag is held back until the next
+// chunk decides it.
+type ThinkSplitter struct {
+	thinking bool
+	// pending holds the start of a possible tag.
+	pending string
+	// trimLeading drops the whitespace models put after a tag.
+	trimLeading bool
+}
+
+// Split returns the answer text and the thinking found in the next chunk.
+func (s *ThinkSplitter) Split(chunk string) (content, thinking string) {
+	text := s.pending + chunk
+	s.pending = ""
+	var contentOut, thinkingOut strings.Builder
+	for text != "" {
+		tag := "<think>"
+		if s.thinking {
+			tag = "</think>"
+		}
+
+		end := strings.Index(text, tag)
+		rest := ""
+		if end >= 0 {
+			rest = text[end+len(tag):]
+		} else {
+			end = len(text) - partialSuffix(text, tag)
+			s.pending = text[end:]
+		}
+		s.write(&contentOut, &thinkingOut, text[:end])
+		if s.pending != "" || end == len(text) {
+			break
+		}
+		s.thinking = !s.thinking
+		s.trimLeading = true
+		text = rest
+	}
+	return contentOut.String(), thinkingOut.String()
+}
+
+// Flush returns the text held back once the stream has ended.
+func (s *ThinkSplitter) Flush() (content, thinking string) {
+	var contentOut, thinkingOut strings.Builder
+	s.write(&contentOut, &thinkingOut, s.pending)
+	s.pending = ""
+	return contentOut.String(), thinkingOut.String()
+}
+
+func (s *ThinkSplitter) write(content, thinking *strings.Builder, text string) {
+	if s.trimLeading {
+		text = strings.TrimLeft(text, " \t\r\n")
+		if text == "" {
+			return
+		}
+		s.trimLeading = false
+	}
+	if s.thinking {
+		thinking.WriteString(text)
+	} else {
+		content.WriteString(text)
+	}
+}
+
+// partialSuffix returns the length of the longest suffix of text that is a
+// proper prefix of tag.
+func partialSuffix(text, tag string) int {
+	for n := min(len(tag)-1, len(text)); n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
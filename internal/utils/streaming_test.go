@@ -107,3 +107,32 @@ func TestGroupXMLCharByChar(t *testing.T) {
 		}
 	}
 }
+
+func TestThinkSplitter(t *testing.T) {
+	chunks := []string{"<thi", "nk>\nplan ", "the <b>answer</b></th", "ink>\n\nThe ", "answer is 4 <", "3"}
+	splitter := ThinkSplitter{}
+	var content, thinking string
+	for _, chunk := range chunks {
+		c, th := splitter.Split(chunk)
+		content += c
+		thinking += th
+	}
+	c, th := splitter.Flush()
+	content += c
+	thinking += th
+
+	if thinking != "plan the <b>answer</b>" {
+		t.Fatalf("unexpected thinking %q", thinking)
+	}
+	if content != "The answer is 4 <3" {
+		t.Fatalf("unexpected content %q", content)
+	}
+}
+
+func TestThinkSplitterWithoutTags(t *testing.T) {
+	splitter := ThinkSplitter{}
+	content, thinking := splitter.Split("  plain answer")
+	if content != "  plain answer" || thinking != "" {
+		t.Fatalf("expected untouched content, got %q and %q", content, thinking)
+	}
+}
@@ -0,0 +1,100 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/logkn/agents-go/internal/runner"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// Reducer combines the results of a parallel workflow's steps, in step
+// order, into the workflow's answer.
+type Reducer func(results []Result) (string, error)
+
+// JoinOutputs is the default Reducer. It lists each step's output under a
+// heading naming the step.
+func JoinOutputs(results []Result) (string, error) {
+	sections := make([]string, len(results))
+	for i, result := range results {
+		sections[i] = "## " + result.Name + "\n\n" + result.Output
+	}
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// ParallelWorkflow runs its steps side by side on the same input.
+type ParallelWorkflow[Context any] struct {
+	name   string
+	steps  []Runnable[Context]
+	reduce Reducer
+	judge  Runnable[Context]
+}
+
+// Parallel returns a workflow running steps concurrently on its input. Their
+// results are combined with JoinOutputs unless a reducer or judge is set.
+func Parallel[Context any](name string, steps ...Runnable[Context]) *ParallelWorkflow[Context] {
+	return &ParallelWorkflow[Context]{name: name, steps: steps, reduce: JoinOutputs}
+}
+
+// WithReducer combines the steps' results with reduce.
+func (w *ParallelWorkflow[Context]) WithReducer(reduce Reducer) *ParallelWorkflow[Context] {
+	w.reduce = reduce
+	return w
+}
+
+// WithJudge has judge combine the steps' results: it is shown the
+// conversation followed by their outputs, and its answer is the workflow's.
+// A judge takes precedence over a reducer.
+func (w *ParallelWorkflow[Context]) WithJudge(judge Runnable[Context]) *ParallelWorkflow[Context] {
+	w.judge = judge
+	return w
+}
+
+func (w *ParallelWorkflow[Context]) Name() string {
+	return w.name
+}
+
+// Run starts the workflow. It fails if any of the steps does, once all of
+// them have finished.
+func (w *ParallelWorkflow[Context]) Run(runCtx context.Context, input runner.Input, ctx *Context, opts ...runner.RunOption) (*runner.AgentResponse, error) {
+	if len(w.steps) == 0 {
+		return nil, ErrNoSteps
+	}
+	return runner.Group(runCtx, w.name, input, func(g *runner.GroupRun, messages []types.Message) (runner.GroupResult, error) {
+		results := make([]Result, len(w.steps))
+		errs := make([]error, len(w.steps))
+		var wg sync.WaitGroup
+		for i, step := range w.steps {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], errs[i] = runStep(g, step, runner.Input{OfMessages: messages}, ctx)
+			}()
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil {
+			return runner.GroupResult{}, err
+		}
+
+		if w.judge != nil {
+			candidates, _ := JoinOutputs(results)
+			prompt := "Several agents answered the conversation above independently. " +
+				"Reply with the single best answer, combining theirs where they complement each other.\n\n" + candidates
+			judged, err := runStep(g, w.judge, runner.Input{OfMessages: append(slices.Clone(messages), types.NewUserMessage(prompt))}, ctx)
+			if err != nil {
+				return runner.GroupResult{}, err
+			}
+			return judged.groupResult(), nil
+		}
+
+		answer, err := w.reduce(results)
+		if err != nil {
+			return runner.GroupResult{}, fmt.Errorf("failed to combine results: %w", err)
+		}
+		return runner.GroupResult{Answer: types.NewAssistantMessage(answer, w.name, nil)}, nil
+	}, opts...)
+}
@@ -0,0 +1,103 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/logkn/agents-go/internal/runner"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// ErrUnknownRoute is reported when a classifier picks a route the router does
+// not have and no fallback is set.
+var ErrUnknownRoute = errors.New("unknown route")
+
+// Classifier picks the route, by name, that should handle a conversation.
+// Runs it starts with runCtx are part of the router's.
+type Classifier[Context any] func(runCtx context.Context, messages []types.Message, routes []string, ctx *Context) (string, error)
+
+// RouteChoice is the answer of a classifier agent.
+type RouteChoice struct {
+	Route string `json:"route" description:"The name of the route that should handle the conversation"`
+}
+
+// ClassifyWith uses agent as a classifier. It is shown the conversation
+// followed by the names of the routes, and answers with a RouteChoice.
+func ClassifyWith[Context any](agent types.Agent[Context]) Classifier[Context] {
+	agent.OutputType = RouteChoice{}
+	return func(runCtx context.Context, messages []types.Message, routes []string, ctx *Context) (string, error) {
+		prompt := "Pick the route that should handle the conversation above. Routes: " + strings.Join(routes, ", ")
+		resp, err := runner.Run(runCtx, agent, runner.Input{OfMessages: append(slices.Clone(messages), types.NewUserMessage(prompt))}, ctx)
+		if err != nil {
+			return "", err
+		}
+		choice, err := runner.FinalOutput[RouteChoice](resp)
+		return choice.Route, err
+	}
+}
+
+// RouterWorkflow hands the conversation to one of its routes.
+type RouterWorkflow[Context any] struct {
+	name     string
+	classify Classifier[Context]
+	routes   []Runnable[Context]
+	fallback string
+}
+
+// Router returns a workflow that has classify pick one of routes and runs it
+// on the workflow's input. The route's answer is the workflow's.
+func Router[Context any](name string, classify Classifier[Context], routes ...Runnable[Context]) *RouterWorkflow[Context] {
+	return &RouterWorkflow[Context]{name: name, classify: classify, routes: routes}
+}
+
+// WithFallback runs the named route when the classifier picks one the router
+// does not have.
+func (w *RouterWorkflow[Context]) WithFallback(route string) *RouterWorkflow[Context] {
+	w.fallback = route
+	return w
+}
+
+func (w *RouterWorkflow[Context]) Name() string {
+	return w.name
+}
+
+// route returns the route called name, or the fallback.
+func (w *RouterWorkflow[Context]) route(name string) (Runnable[Context], bool) {
+	for _, candidate := range []string{name, w.fallback} {
+		for _, route := range w.routes {
+			if candidate != "" && route.Name() == candidate {
+				return route, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Run starts the workflow.
+func (w *RouterWorkflow[Context]) Run(runCtx context.Context, input runner.Input, ctx *Context, opts ...runner.RunOption) (*runner.AgentResponse, error) {
+	if len(w.routes) == 0 {
+		return nil, ErrNoSteps
+	}
+	names := make([]string, len(w.routes))
+	for i, route := range w.routes {
+		names[i] = route.Name()
+	}
+	return runner.Group(runCtx, w.name, input, func(g *runner.GroupRun, messages []types.Message) (runner.GroupResult, error) {
+		picked, err := w.classify(g.Context("classifier"), messages, names, ctx)
+		if err != nil {
+			return runner.GroupResult{}, fmt.Errorf("failed to classify: %w", err)
+		}
+		route, ok := w.route(picked)
+		if !ok {
+			return runner.GroupResult{}, fmt.Errorf("%w %q", ErrUnknownRoute, picked)
+		}
+		result, err := runStep(g, route, runner.Input{OfMessages: messages}, ctx)
+		if err != nil {
+			return runner.GroupResult{}, err
+		}
+		return result.groupResult(), nil
+	}, opts...)
+}
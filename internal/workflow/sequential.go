@@ -0,0 +1,45 @@
+package workflow
+
+import (
+	"context"
+
+	"github.com/logkn/agents-go/internal/runner"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// SequentialWorkflow runs its steps one after the other, as a pipeline.
+type SequentialWorkflow[Context any] struct {
+	name  string
+	steps []Runnable[Context]
+}
+
+// Sequential returns a workflow running steps in order. The first is given
+// the workflow's input; each of the others the output of the one before it.
+// The last step's answer is the workflow's.
+func Sequential[Context any](name string, steps ...Runnable[Context]) *SequentialWorkflow[Context] {
+	return &SequentialWorkflow[Context]{name: name, steps: steps}
+}
+
+func (w *SequentialWorkflow[Context]) Name() string {
+	return w.name
+}
+
+// Run starts the workflow. It stops at the first step that fails.
+func (w *SequentialWorkflow[Context]) Run(runCtx context.Context, input runner.Input, ctx *Context, opts ...runner.RunOption) (*runner.AgentResponse, error) {
+	if len(w.steps) == 0 {
+		return nil, ErrNoSteps
+	}
+	return runner.Group(runCtx, w.name, input, func(g *runner.GroupRun, messages []types.Message) (runner.GroupResult, error) {
+		var last Result
+		next := runner.Input{OfMessages: messages}
+		for _, step := range w.steps {
+			result, err := runStep(g, step, next, ctx)
+			if err != nil {
+				return runner.GroupResult{}, err
+			}
+			last = result
+			next = runner.Input{OfString: result.Output}
+		}
+		return last.groupResult(), nil
+	}, opts...)
+}
@@ -0,0 +1,75 @@
+// Package workflow coordinates several agents as a single run: side by side,
+// one after the other, or by routing the conversation to one of them. Every
+// workflow reports through one AgentResponse, like a run of a single agent.
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/logkn/agents-go/internal/runner"
+	"github.com/logkn/agents-go/internal/types"
+	"github.com/logkn/agents-go/internal/utils"
+)
+
+// ErrNoSteps is returned when running a workflow without steps.
+var ErrNoSteps = errors.New("workflow has no steps")
+
+// Runnable is an agent, or a workflow of agents, that can be run on an
+// input. Workflows are Runnable, so they can be steps of other workflows.
+type Runnable[Context any] interface {
+	Name() string
+	Run(runCtx context.Context, input runner.Input, ctx *Context, opts ...runner.RunOption) (*runner.AgentResponse, error)
+}
+
+type agentRunnable[Context any] struct {
+	agent types.Agent[Context]
+}
+
+// Agent makes agent a workflow step.
+func Agent[Context any](agent types.Agent[Context]) Runnable[Context] {
+	return agentRunnable[Context]{agent: agent}
+}
+
+func (a agentRunnable[Context]) Name() string {
+	return a.agent.Name
+}
+
+func (a agentRunnable[Context]) Run(runCtx context.Context, input runner.Input, ctx *Context, opts ...runner.RunOption) (*runner.AgentResponse, error) {
+	return runner.Run(runCtx, a.agent, input, ctx, opts...)
+}
+
+// Result is the outcome of one step of a workflow.
+type Result struct {
+	// Name is the step's name.
+	Name string
+	// Output is the step's answer: its structured output as JSON, or the
+	// text of its last message.
+	Output   string
+	Response *runner.AgentResponse
+}
+
+// groupResult makes the step's answer the workflow's.
+func (r Result) groupResult() runner.GroupResult {
+	output, _ := runner.FinalOutput[any](r.Response)
+	return runner.GroupResult{Answer: r.Response.Response(), Output: output}
+}
+
+// runStep runs step as part of g and waits for it to finish. Steps that do
+// not complete fail the workflow.
+func runStep[Context any](g *runner.GroupRun, step Runnable[Context], input runner.Input, ctx *Context) (Result, error) {
+	resp, err := step.Run(g.Context(step.Name()), input, ctx, g.RunOptions()...)
+	if err != nil {
+		return Result{}, fmt.Errorf("step %s: %w", step.Name(), err)
+	}
+	if status := resp.Status(); status != runner.StatusCompleted {
+		return Result{}, fmt.Errorf("step %s %s: %w", step.Name(), status, resp.Err())
+	}
+
+	output := resp.Response().Content
+	if structured, err := runner.FinalOutput[any](resp); err == nil {
+		output = utils.AsString(structured)
+	}
+	return Result{Name: step.Name(), Output: output, Response: resp}, nil
+}
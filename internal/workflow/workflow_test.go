@@ -0,0 +1,169 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/logkn/agents-go/internal/runner"
+	"github.com/logkn/agents-go/internal/tracing"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+// replyProvider answers every request with reply.
+type replyProvider struct {
+	reply func(req types.CompletionRequest) types.CompletionChunk
+}
+
+func (p replyProvider) Stream(_ context.Context, req types.CompletionRequest) (types.CompletionStream, error) {
+	return &replyStream{chunk: p.reply(req)}, nil
+}
+
+type replyStream struct {
+	chunk types.CompletionChunk
+	done  bool
+}
+
+func (s *replyStream) Next() bool {
+	next := !s.done
+	s.done = true
+	return next
+}
+
+func (s *replyStream) Current() types.CompletionChunk { return s.chunk }
+func (s *replyStream) Err() error                     { return nil }
+func (s *replyStream) Close() error                   { return nil }
+
+// wrapper returns an agent answering with the last message it was given,
+// wrapped in its name.
+func wrapper(name string) Runnable[struct{}] {
+	provider := replyProvider{reply: func(req types.CompletionRequest) types.CompletionChunk {
+		last := req.Messages[len(req.Messages)-1]
+		return types.CompletionChunk{Content: name + "(" + last.Content + ")"}
+	}}
+	return Agent(*types.NewAgent[struct{}](name, types.ModelConfig{Model: "fake", Provider: provider}))
+}
+
+// spanRecorder keeps the spans that end.
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []tracing.Span
+}
+
+func (r *spanRecorder) OnStart(tracing.Span) {}
+func (r *spanRecorder) OnEnd(span tracing.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+func (r *spanRecorder) Shutdown(context.Context) error { return nil }
+
+func TestSequential(t *testing.T) {
+	recorder := &spanRecorder{}
+	pipeline := Sequential("pipeline", wrapper("draft"), wrapper("edit"))
+	resp, err := pipeline.Run(context.Background(), runner.Input{OfString: "topic"}, &struct{}{},
+		runner.WithTracer(tracing.NewTracer(recorder)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var steps []string
+	for event := range resp.Stream() {
+		if _, ok := event.RunStarted(); ok && event.Step != "" {
+			steps = append(steps, event.Step)
+		}
+	}
+	if status := resp.Status(); status != runner.StatusCompleted {
+		t.Fatalf("expected completed run, got %v: %v", status, resp.Err())
+	}
+	if !slices.Equal(steps, []string{"draft", "edit"}) {
+		t.Fatalf("expected the steps' events to be forwarded in order, got %v", steps)
+	}
+	conversation := resp.FinalConversation()
+	if len(conversation) != 2 || conversation[1].Content != "edit(draft(topic))" {
+		t.Fatalf("unexpected conversation %+v", conversation)
+	}
+	if chain := resp.HandoffChain(); !slices.Equal(chain, []string{"draft", "edit"}) {
+		t.Fatalf("unexpected agents %v", chain)
+	}
+
+	runs := map[string]tracing.Span{}
+	for _, span := range recorder.spans {
+		if span.Kind == tracing.KindRun {
+			runs[span.Name] = span
+		}
+	}
+	group := runs["pipeline"]
+	if len(runs) != 3 || runs["draft"].ParentID != group.SpanID || runs["edit"].ParentID != group.SpanID {
+		t.Fatalf("expected the steps to be traced within the workflow, got %+v", runs)
+	}
+}
+
+func TestParallel(t *testing.T) {
+	steps := []Runnable[struct{}]{wrapper("a"), wrapper("b")}
+	tests := []struct {
+		name     string
+		workflow *ParallelWorkflow[struct{}]
+		want     string
+	}{
+		{"join", Parallel("fanout", steps...), "## a\n\na(q)\n\n## b\n\nb(q)"},
+		{"reducer", Parallel("fanout", steps...).WithReducer(func(results []Result) (string, error) {
+			return results[0].Output + "+" + results[1].Output, nil
+		}), "a(q)+b(q)"},
+		{"judge", Parallel("fanout", steps...).WithJudge(wrapper("judge")), "## a\n\na(q)\n\n## b\n\nb(q))"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.workflow.Run(context.Background(), runner.Input{OfString: "q"}, &struct{}{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if status := resp.Status(); status != runner.StatusCompleted {
+				t.Fatalf("expected completed run, got %v: %v", status, resp.Err())
+			}
+			if answer := resp.Response().Content; !strings.HasSuffix(answer, tt.want) {
+				t.Fatalf("expected answer ending with %q, got %q", tt.want, answer)
+			}
+		})
+	}
+}
+
+func TestRouter(t *testing.T) {
+	classifier := func(route string) Classifier[struct{}] {
+		provider := replyProvider{reply: func(types.CompletionRequest) types.CompletionChunk {
+			return types.CompletionChunk{ToolCalls: []types.ToolCallDelta{{Index: 0, ID: "call_1", Name: "final_output", Args: `{"route":"` + route + `"}`}}}
+		}}
+		return ClassifyWith(*types.NewAgent[struct{}]("classifier", types.ModelConfig{Model: "fake", Provider: provider}))
+	}
+
+	tests := []struct {
+		name     string
+		workflow *RouterWorkflow[struct{}]
+		want     string
+		err      error
+	}{
+		{"route", Router("support", classifier("billing"), wrapper("billing"), wrapper("general")), "billing(help)", nil},
+		{"fallback", Router("support", classifier("refunds"), wrapper("billing"), wrapper("general")).WithFallback("general"), "general(help)", nil},
+		{"unknown", Router("support", classifier("refunds"), wrapper("billing"), wrapper("general")), "", ErrUnknownRoute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.workflow.Run(context.Background(), runner.Input{OfString: "help"}, &struct{}{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.err != nil {
+				if status := resp.Status(); status != runner.StatusFailed || !errors.Is(resp.Err(), tt.err) {
+					t.Fatalf("expected failure with %v, got %v: %v", tt.err, status, resp.Err())
+				}
+				return
+			}
+			if answer := resp.Response().Content; answer != tt.want {
+				t.Fatalf("expected %q, got %q (%v)", tt.want, answer, resp.Err())
+			}
+		})
+	}
+}
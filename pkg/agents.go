@@ -2,8 +2,6 @@
 package agents
 
 import (
-	"fmt"
-
 	"github.com/logkn/agents-go/internal/runner"
 	"github.com/logkn/agents-go/internal/tools"
 	"github.com/logkn/agents-go/internal/types"
@@ -18,6 +16,7 @@ type (
 	ToolArgs[Context any]       = tools.ToolArgs[Context]
 	Input                       = runner.Input
 	AgentResponse               = runner.AgentResponse
+	RunStatus                   = runner.RunStatus
 	Role                        = types.Role
 )
 
@@ -29,35 +28,20 @@ const (
 	ToolRole  = types.Tool
 )
 
-// agentToolArgs represents the parameters required when running an Agent as a
-// tool. The embedded agent field is ignored when generating a JSON schema and
-// when unmarshalling parameters.
-type agentToolArgs[Context any] struct {
-	// Prompt is the user input passed to the nested agent.
-	Prompt string
-
-	agent types.Agent[Context] `json:"-"`
-}
-
-// Run executes the wrapped agent using the provided prompt and returns the
-// final assistant response content. Errors are returned as strings.
-func (a agentToolArgs[Context]) Run(ctx *Context) any {
-	resp, err := runner.Run(a.agent, runner.Input{OfString: a.Prompt}, nil)
-	if err != nil {
-		return fmt.Sprintf("error running agent: %v", err)
-	}
-	return resp.Response().Content
-}
+// Run statuses
+const (
+	StatusRunning   = runner.StatusRunning
+	StatusCompleted = runner.StatusCompleted
+	StatusCancelled = runner.StatusCancelled
+	StatusFailed    = runner.StatusFailed
+)
 
-// AsTool exposes the agent as an executable Tool. The returned Tool accepts a
-// single parameter `prompt` which is used as the input for the agent. When the
-// tool is invoked, the agent is run and the final response text is returned.
-func AsTool[Context any](a Agent[Context], toolname, description string) tools.Tool[Context] {
-	return tools.Tool[Context]{
-		Name:        toolname,
-		Description: description,
-		Args:        agentToolArgs[Context]{agent: a},
-	}
+// AsTool exposes the agent as an executable Tool taking a single `prompt`
+// parameter. Each call runs the agent on the prompt with the caller's Context
+// as part of the calling run, forwarding its events to the caller's stream,
+// and returns its final answer unless an OutputExtractor is given.
+func AsTool[Context any](a Agent[Context], toolname, description string, opts ...AgentToolOption) tools.Tool[Context] {
+	return runner.AgentTool(a, toolname, description, opts...)
 }
 
 func NewAgent[Context any](model Model) *Agent[Context] {
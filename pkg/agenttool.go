@@ -0,0 +1,40 @@
+package agents
+
+import "github.com/logkn/agents-go/internal/runner"
+
+type (
+	AgentToolConfig = runner.AgentToolConfig
+	AgentToolOption = runner.AgentToolOption
+	// OutputExtractor turns a finished run of an agent exposed as a tool
+	// into the tool's result.
+	OutputExtractor = runner.OutputExtractor
+)
+
+// WithAgentToolMaxTurns caps the LLM calls of each run of an agent exposed as
+// a tool.
+func WithAgentToolMaxTurns(maxTurns int) AgentToolOption {
+	return runner.WithAgentToolMaxTurns(maxTurns)
+}
+
+// WithAgentToolOutput sets how a run of an agent exposed as a tool becomes the
+// tool's result.
+func WithAgentToolOutput(extract OutputExtractor) AgentToolOption {
+	return runner.WithAgentToolOutput(extract)
+}
+
+// ExtractFinalAnswer returns the text of the agent's last message. It is the
+// default OutputExtractor.
+func ExtractFinalAnswer(resp *AgentResponse) (any, error) {
+	return runner.ExtractFinalAnswer(resp)
+}
+
+// ExtractStructuredOutput returns the structured output of an agent with an
+// OutputType.
+func ExtractStructuredOutput(resp *AgentResponse) (any, error) {
+	return runner.ExtractStructuredOutput(resp)
+}
+
+// ExtractLastMessages returns the last n messages of the agent's run.
+func ExtractLastMessages(n int) OutputExtractor {
+	return runner.ExtractLastMessages(n)
+}
@@ -0,0 +1,40 @@
+package agents
+
+import (
+	"github.com/logkn/agents-go/internal/runner"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+type (
+	ApprovalPolicy[Context any] = types.ApprovalPolicy[Context]
+	ApprovalDecision            = runner.ApprovalDecision
+	ToolCall                    = types.ToolCall
+)
+
+var (
+	// ErrToolCallRejected is the tool result of a call rejected during
+	// approval.
+	ErrToolCallRejected = runner.ErrToolCallRejected
+	// ErrNoPendingApproval is returned when resolving a tool call that is
+	// not waiting for approval.
+	ErrNoPendingApproval = runner.ErrNoPendingApproval
+	// ErrAmbiguousApproval is returned when resolving a tool call by an ID
+	// that calls of several runs waiting for approval share.
+	ErrAmbiguousApproval = runner.ErrAmbiguousApproval
+)
+
+// RequireApprovalForMarked holds calls to tools marked NeedsApproval. Agents
+// without an approval policy use it.
+func RequireApprovalForMarked[Context any](ctx *Context, tool Tool[Context], call ToolCall) bool {
+	return types.RequireApprovalForMarked(ctx, tool, call)
+}
+
+// RequireApprovalForAll holds every tool call for approval.
+func RequireApprovalForAll[Context any](ctx *Context, tool Tool[Context], call ToolCall) bool {
+	return types.RequireApprovalForAll(ctx, tool, call)
+}
+
+// RequireApprovalForNone lets every tool call run unattended.
+func RequireApprovalForNone[Context any](ctx *Context, tool Tool[Context], call ToolCall) bool {
+	return types.RequireApprovalForNone(ctx, tool, call)
+}
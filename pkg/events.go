@@ -0,0 +1,32 @@
+package agents
+
+import (
+	"github.com/logkn/agents-go/internal/runner"
+	"github.com/logkn/agents-go/internal/utils"
+)
+
+// Events streamed by AgentResponse.Stream. Each AgentEvent carries the run
+// ID, turn and active agent alongside one of these payloads.
+type (
+	AgentEvent             = runner.AgentEvent
+	ToolResult             = runner.ToolResult
+	RunStartedEvent        = runner.RunStartedEvent
+	TurnStartedEvent       = runner.TurnStartedEvent
+	LLMRequestSentEvent    = runner.LLMRequestSentEvent
+	ToolCallDeltaEvent     = runner.ToolCallDeltaEvent
+	ToolCallStartedEvent   = runner.ToolCallStartedEvent
+	ToolCallFinishedEvent  = runner.ToolCallFinishedEvent
+	ApprovalRequestedEvent = runner.ApprovalRequestedEvent
+	AgentSwitchedEvent     = runner.AgentSwitchedEvent
+	HandoffEvent           = runner.HandoffEvent
+	RetryEvent             = runner.RetryEvent
+	UsageEvent             = runner.UsageEvent
+	CompactionEvent        = runner.CompactionEvent
+	RunCompletedEvent      = runner.RunCompletedEvent
+)
+
+// ParsePartialJSON parses JSON that may have been cut off, such as the
+// arguments of a ToolCallDeltaEvent, closing what is left open.
+func ParsePartialJSON(s string) (any, error) {
+	return utils.ParsePartialJSON(s)
+}
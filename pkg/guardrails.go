@@ -0,0 +1,60 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/logkn/agents-go/internal/runner"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+type (
+	Guardrail[Context any] = types.Guardrail[Context]
+	GuardrailResult        = types.GuardrailResult
+	GuardrailTripped       = runner.GuardrailTripped
+	GuardrailStage         = runner.GuardrailStage
+)
+
+// Guardrail stages
+const (
+	GuardrailInput  = runner.GuardrailInput
+	GuardrailOutput = runner.GuardrailOutput
+)
+
+// ErrGuardrailTripped matches a run ended by a tripped guardrail.
+var ErrGuardrailTripped = runner.ErrGuardrailTripped
+
+// NewGuardrail builds a guardrail from a plain predicate, which trips the
+// guardrail with reason whenever it returns true.
+func NewGuardrail[Context any](name, reason string, tripped func(ctx *Context, text string) bool) Guardrail[Context] {
+	return types.NewGuardrail(name, reason, tripped)
+}
+
+// guardrailVerdict is the structured answer of a classifier agent.
+type guardrailVerdict struct {
+	// Tripped is true when the text violates the policy.
+	Tripped bool `json:"tripped"`
+	// Reason briefly explains the verdict.
+	Reason string `json:"reason"`
+}
+
+// AgentGuardrail uses classifier as a guardrail. The classifier receives the
+// checked text as its prompt and should be instructed with the policy to
+// enforce; it answers with whether the text violates it and why.
+func AgentGuardrail[Context any](name string, classifier Agent[Context]) Guardrail[Context] {
+	classifier.OutputType = guardrailVerdict{}
+	return Guardrail[Context]{
+		Name: name,
+		Check: func(runCtx context.Context, ctx *Context, text string) (GuardrailResult, error) {
+			resp, err := runner.Run(runCtx, classifier, runner.Input{OfString: text}, ctx)
+			if err != nil {
+				return GuardrailResult{}, err
+			}
+			verdict, err := runner.FinalOutput[guardrailVerdict](resp)
+			if err != nil {
+				return GuardrailResult{}, fmt.Errorf("classifier %s: %w", classifier.Name, err)
+			}
+			return GuardrailResult{Tripped: verdict.Tripped, Reason: verdict.Reason}, nil
+		},
+	}
+}
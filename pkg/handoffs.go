@@ -0,0 +1,41 @@
+package agents
+
+import (
+	"github.com/logkn/agents-go/internal/runner"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+type (
+	HandoffPrompt     = types.HandoffPrompt
+	HandoffPromptMode = types.HandoffPromptMode
+	HandoffReturn     = types.HandoffReturn
+	HandoffCaller     = runner.HandoffCaller
+	// HandoffCyclePolicy decides what happens to a handoff back to an agent
+	// that is active or waiting for a handoff to return, or to a transfer
+	// repeated within a run.
+	HandoffCyclePolicy = runner.HandoffCyclePolicy
+)
+
+// How the prompt given with a handoff reaches the receiving agent.
+const (
+	PromptAsUserMessage = types.PromptAsUserMessage
+	PromptAsSystemNote  = types.PromptAsSystemNote
+)
+
+// Handoff cycle policies.
+const (
+	HandoffCycleRefuse = runner.HandoffCycleRefuse
+	HandoffCycleFail   = runner.HandoffCycleFail
+	HandoffCycleAllow  = runner.HandoffCycleAllow
+)
+
+// ErrHandoffCycle is reported when a handoff would go back to an agent that
+// is active or waiting for a handoff to return, or repeat a transfer made
+// earlier in the run.
+var ErrHandoffCycle = runner.ErrHandoffCycle
+
+// OnHandoffInput adapts a callback taking the handoff's input as a T, the
+// type given as InputType, for use as OnHandoff.
+func OnHandoffInput[Context, T any](fn func(ctx *Context, input T) error) func(ctx *Context, input any) error {
+	return types.OnHandoffInput(fn)
+}
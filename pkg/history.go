@@ -0,0 +1,43 @@
+package agents
+
+import (
+	"github.com/logkn/agents-go/internal/history"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+type (
+	HistoryStrategy = types.HistoryStrategy
+	HistoryFunc     = types.HistoryFunc
+	Summarizer      = history.Summarizer
+)
+
+// KeepLastTurns keeps only the last n turns of the conversation, a turn
+// starting with each user message.
+func KeepLastTurns(n int) HistoryStrategy {
+	return history.LastTurns(n)
+}
+
+// LastMessages keeps the n most recent messages, never separating a tool
+// call from its results.
+func LastMessages(n int) HistoryStrategy {
+	return history.LastMessages(n)
+}
+
+// DropToolCalls removes tool calls and their results, keeping only what was
+// said. It suits handoffs whose receiving agent should not see the previous
+// agent's work.
+func DropToolCalls() HistoryStrategy {
+	return history.DropToolCalls()
+}
+
+// TokenBudget keeps the most recent messages that fit in maxTokens, never
+// separating a tool call from its results.
+func TokenBudget(maxTokens int) HistoryStrategy {
+	return history.TokenBudget(maxTokens)
+}
+
+// Summarize has model summarize older turns once the conversation grows past
+// maxTokens.
+func Summarize(model Model, maxTokens int) *Summarizer {
+	return history.Summarize(model, maxTokens)
+}
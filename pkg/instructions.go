@@ -13,3 +13,9 @@ func StringInstructions[Context any](s string) Instructions[Context] {
 func FileInstructions[Context any](file string) Instructions[Context] {
 	return types.AgentInstructions[Context]{OfFile: file}
 }
+
+// FuncInstructions builds an agent's instructions with fn before every LLM
+// call.
+func FuncInstructions[Context any](fn func(ctx *Context, agent *Agent[Context]) (string, error)) Instructions[Context] {
+	return types.FuncInstructions(fn)
+}
@@ -1,10 +1,23 @@
 package agents
 
-import "github.com/logkn/agents-go/internal/types"
+import (
+	"time"
+
+	"github.com/logkn/agents-go/internal/types"
+)
 
 type (
 	Model       = types.ModelConfig
 	ModelOption = types.ModelOption
+	RetryPolicy = types.RetryPolicy
+	ModelPrice  = types.ModelPrice
+)
+
+// Tool choices accepted by WithToolChoice, besides the name of a tool.
+const (
+	ToolChoiceAuto     = types.ToolChoiceAuto
+	ToolChoiceNone     = types.ToolChoiceNone
+	ToolChoiceRequired = types.ToolChoiceRequired
 )
 
 func NewModel(model string, opts ...types.ModelOption) Model {
@@ -28,7 +41,175 @@ func WithBaseURL(baseURL string) ModelOption {
 
 func WithTemperature(temperature float32) ModelOption {
 	return modelOptionFunc(func(config *Model) error {
-		config.Temperature = temperature
+		config.Temperature = &temperature
+		return nil
+	})
+}
+
+func WithProvider(provider Provider) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.Provider = provider
+		return nil
+	})
+}
+
+func WithThinkingBudget(tokens int) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.ThinkingBudget = tokens
+		return nil
+	})
+}
+
+func WithThink(think bool) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.Think = &think
+		return nil
+	})
+}
+
+func WithKeepAlive(keepAlive time.Duration) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.KeepAlive = keepAlive
+		return nil
+	})
+}
+
+func WithNumCtx(numCtx int) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.NumCtx = numCtx
+		return nil
+	})
+}
+
+// WithOption sets a provider-specific model option such as Ollama's
+// num_predict.
+func WithOption(key string, value any) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		if config.Options == nil {
+			config.Options = map[string]any{}
+		}
+		config.Options[key] = value
+		return nil
+	})
+}
+
+// WithRetryPolicy sets how failed requests to the model are retried.
+func WithRetryPolicy(policy RetryPolicy) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.Retry = policy
+		return nil
+	})
+}
+
+// WithMaxTokens caps the tokens generated per turn.
+func WithMaxTokens(maxTokens int) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.MaxTokens = maxTokens
+		return nil
+	})
+}
+
+func WithTopP(topP float32) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.TopP = &topP
+		return nil
+	})
+}
+
+func WithSeed(seed int64) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.Seed = &seed
+		return nil
+	})
+}
+
+// WithStop sets the sequences that end generation.
+func WithStop(sequences ...string) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.Stop = sequences
+		return nil
+	})
+}
+
+// WithToolChoice sets whether the model may, must or must not call tools:
+// one of the ToolChoice constants, or the name of the tool to call.
+func WithToolChoice(choice string) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.ToolChoice = choice
 		return nil
 	})
 }
+
+func WithParallelToolCalls(parallel bool) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.ParallelToolCalls = &parallel
+		return nil
+	})
+}
+
+// WithReasoningEffort sets the reasoning effort ("low", "medium" or "high")
+// of reasoning models.
+func WithReasoningEffort(effort string) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.ReasoningEffort = effort
+		return nil
+	})
+}
+
+// WithSendReasoning replays the model's earlier reasoning to it on later
+// turns. By default reasoning is kept on the messages but not sent back.
+func WithSendReasoning(send bool) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.SendReasoning = send
+		return nil
+	})
+}
+
+func WithAPIKey(apiKey string) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.APIKey = apiKey
+		return nil
+	})
+}
+
+// WithAPIKeyEnv reads the API key from the named environment variable.
+func WithAPIKeyEnv(name string) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.APIKeyEnv = name
+		return nil
+	})
+}
+
+// WithHeader adds a header to every request made for the model.
+func WithHeader(key, value string) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		if config.ExtraHeaders == nil {
+			config.ExtraHeaders = map[string]string{}
+		}
+		config.ExtraHeaders[key] = value
+		return nil
+	})
+}
+
+// WithRequestTimeout caps each LLM request, including streaming its response.
+func WithRequestTimeout(timeout time.Duration) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.RequestTimeout = timeout
+		return nil
+	})
+}
+
+// WithPricing sets the price used to estimate the model's costs, e.g. for a
+// self-hosted model billed by a provider.
+func WithPricing(price ModelPrice) ModelOption {
+	return modelOptionFunc(func(config *Model) error {
+		config.Pricing = &price
+		return nil
+	})
+}
+
+// SetModelPrice adds or replaces a model in the pricing table used to
+// estimate run costs. Models missing from the table cost nothing.
+func SetModelPrice(model string, price ModelPrice) {
+	types.SetModelPrice(model, price)
+}
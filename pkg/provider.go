@@ -0,0 +1,33 @@
+package agents
+
+import (
+	"github.com/logkn/agents-go/internal/providers"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+type (
+	Provider          = types.Provider
+	CompletionRequest = types.CompletionRequest
+	CompletionStream  = types.CompletionStream
+	CompletionChunk   = types.CompletionChunk
+	ToolCallDelta     = types.ToolCallDelta
+	Usage             = types.Usage
+	ResponseFormat    = types.ResponseFormat
+	ResponseFormatter = types.ResponseFormatter
+)
+
+// OpenAIProvider serves models through the OpenAI Chat Completions API or a
+// compatible server. It is the default when a model has no provider.
+func OpenAIProvider() Provider {
+	return providers.NewOpenAI()
+}
+
+// AnthropicProvider serves models through the Anthropic Messages API.
+func AnthropicProvider() Provider {
+	return providers.NewAnthropic()
+}
+
+// OllamaProvider serves models through a local Ollama server's native API.
+func OllamaProvider() Provider {
+	return providers.NewOllama()
+}
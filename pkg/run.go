@@ -1 +1,94 @@
 package agents
+
+import (
+	"context"
+	"time"
+
+	"github.com/logkn/agents-go/internal/runner"
+)
+
+// Run executes the agent against the input and streams the results through
+// the returned AgentResponse. Cancelling runCtx, or calling Stop on the
+// response, aborts the run. Options set per-run limits.
+func Run[Context any](runCtx context.Context, agent Agent[Context], input Input, ctx *Context, opts ...RunOption) (*AgentResponse, error) {
+	return runner.Run(runCtx, agent, input, ctx, opts...)
+}
+
+type (
+	RunConfig = runner.RunConfig
+	RunOption = runner.RunOption
+)
+
+// Errors reported when a run exceeds one of its limits.
+var (
+	ErrMaxTurnsExceeded     = runner.ErrMaxTurnsExceeded
+	ErrMaxToolCallsExceeded = runner.ErrMaxToolCallsExceeded
+	ErrMaxTokensExceeded    = runner.ErrMaxTokensExceeded
+	ErrDeadlineExceeded     = runner.ErrDeadlineExceeded
+	ErrMaxHandoffDepth      = runner.ErrMaxHandoffDepth
+)
+
+// ErrRequestTimeout is reported when an LLM request exceeds the model's
+// RequestTimeout.
+var ErrRequestTimeout = runner.ErrRequestTimeout
+
+// ErrEmptyResponse is reported when the LLM answers with nothing.
+var ErrEmptyResponse = runner.ErrEmptyResponse
+
+// ErrInstructions is reported when an agent's instructions cannot be
+// rendered.
+var ErrInstructions = runner.ErrInstructions
+
+// ErrInvalidOutput is reported when the LLM repeatedly fails to answer with
+// the agent's OutputType.
+var ErrInvalidOutput = runner.ErrInvalidOutput
+
+// FinalOutput waits for the run to finish and returns the agent's structured
+// answer as a T, the type given to Agent.WithOutputType.
+func FinalOutput[T any](resp *AgentResponse) (T, error) {
+	return runner.FinalOutput[T](resp)
+}
+
+// WithMaxTurns caps the number of LLM calls in a run.
+func WithMaxTurns(maxTurns int) RunOption {
+	return runner.WithMaxTurns(maxTurns)
+}
+
+// WithMaxToolCalls caps the number of tool calls executed in a run.
+func WithMaxToolCalls(maxToolCalls int) RunOption {
+	return runner.WithMaxToolCalls(maxToolCalls)
+}
+
+// WithMaxTotalTokens caps the tokens consumed across a run.
+func WithMaxTotalTokens(maxTotalTokens int) RunOption {
+	return runner.WithMaxTotalTokens(maxTotalTokens)
+}
+
+// WithTimeout caps the wall-clock duration of a run.
+func WithTimeout(timeout time.Duration) RunOption {
+	return runner.WithTimeout(timeout)
+}
+
+// WithToolConcurrency caps how many tool calls of a turn run at once. Use 1
+// to run every call sequentially.
+func WithToolConcurrency(concurrency int) RunOption {
+	return runner.WithToolConcurrency(concurrency)
+}
+
+// WithSession continues the conversation stored in session and saves the
+// messages the run adds to it.
+func WithSession(session Session) RunOption {
+	return runner.WithSession(session)
+}
+
+// WithMaxHandoffDepth caps how many agents can be waiting for a handoff to
+// return at once.
+func WithMaxHandoffDepth(depth int) RunOption {
+	return runner.WithMaxHandoffDepth(depth)
+}
+
+// WithHandoffCyclePolicy sets how handoffs back to an agent already in the
+// handoff chain are handled.
+func WithHandoffCyclePolicy(policy HandoffCyclePolicy) RunOption {
+	return runner.WithHandoffCyclePolicy(policy)
+}
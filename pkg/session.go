@@ -0,0 +1,25 @@
+package agents
+
+import (
+	"github.com/logkn/agents-go/internal/session"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+type (
+	Session          = types.Session
+	MemorySession    = session.Memory
+	FileSessionStore = session.FileStore
+	FileSession      = session.File
+)
+
+// NewMemorySession returns a session kept in memory for the lifetime of the
+// process.
+func NewMemorySession() *MemorySession {
+	return session.NewMemory()
+}
+
+// NewFileSessionStore returns a store keeping one JSONL file per session ID in
+// dir. Sessions from the store can be shared by concurrent runs.
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	return session.NewFileStore(dir)
+}
@@ -0,0 +1,30 @@
+package agents
+
+import (
+	"context"
+
+	"github.com/logkn/agents-go/internal/runner"
+	"github.com/logkn/agents-go/internal/types"
+)
+
+type (
+	RunState                   = runner.RunState
+	AgentRegistry[Context any] = types.AgentRegistry[Context]
+)
+
+// ErrUnknownAgent is returned when resuming a run whose agent is not in the
+// registry.
+var ErrUnknownAgent = runner.ErrUnknownAgent
+
+// NewAgentRegistry returns a registry holding agents and every agent they
+// can hand off to.
+func NewAgentRegistry[Context any](agents ...*Agent[Context]) *AgentRegistry[Context] {
+	return types.NewAgentRegistry(agents...)
+}
+
+// Resume continues a run from a state returned by AgentResponse.State,
+// looking up its active agent in registry. Tool calls the run was stopped
+// before executing run first.
+func Resume[Context any](runCtx context.Context, registry *AgentRegistry[Context], state RunState, ctx *Context, opts ...RunOption) (*AgentResponse, error) {
+	return runner.Resume(runCtx, registry, state, ctx, opts...)
+}
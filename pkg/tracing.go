@@ -0,0 +1,55 @@
+package agents
+
+import (
+	"github.com/logkn/agents-go/internal/runner"
+	"github.com/logkn/agents-go/internal/tracing"
+)
+
+type (
+	Tracer         = tracing.Tracer
+	TraceProcessor = tracing.Processor
+	Span           = tracing.Span
+	SpanKind       = tracing.SpanKind
+	ActiveSpan     = tracing.ActiveSpan
+	SpanRedactor   = tracing.Redactor
+	JSONLExporter  = tracing.JSONLExporter
+	OTLPExporter   = tracing.OTLPExporter
+	OTLPConfig     = tracing.OTLPConfig
+)
+
+// Kinds of spans recorded for a run.
+const (
+	SpanRun        = tracing.KindRun
+	SpanGeneration = tracing.KindGeneration
+	SpanTool       = tracing.KindTool
+	SpanHandoff    = tracing.KindHandoff
+	SpanGuardrail  = tracing.KindGuardrail
+)
+
+// NewTracer returns a tracer exporting spans to processors. Call its
+// Shutdown before exiting to flush them.
+func NewTracer(processors ...TraceProcessor) *Tracer {
+	return tracing.NewTracer(processors...)
+}
+
+// OmitTraceContent is a SpanRedactor that drops span inputs and outputs.
+func OmitTraceContent(span Span) Span {
+	return tracing.OmitContent(span)
+}
+
+// OpenJSONLExporter returns an exporter appending spans to the file at path.
+func OpenJSONLExporter(path string) (*JSONLExporter, error) {
+	return tracing.OpenJSONLExporter(path)
+}
+
+// NewOTLPExporter returns an exporter sending spans to an OpenTelemetry
+// collector over OTLP/HTTP.
+func NewOTLPExporter(config OTLPConfig) *OTLPExporter {
+	return tracing.NewOTLPExporter(config)
+}
+
+// WithTracer records the run as a trace with nested spans for each LLM call,
+// tool execution, handoff and guardrail.
+func WithTracer(tracer *Tracer) RunOption {
+	return runner.WithTracer(tracer)
+}
@@ -0,0 +1,66 @@
+package agents
+
+import (
+	"context"
+
+	"github.com/logkn/agents-go/internal/runner"
+	"github.com/logkn/agents-go/internal/workflow"
+)
+
+type (
+	Runnable[Context any]           = workflow.Runnable[Context]
+	WorkflowResult                  = workflow.Result
+	Reducer                         = workflow.Reducer
+	Classifier[Context any]         = workflow.Classifier[Context]
+	RouteChoice                     = workflow.RouteChoice
+	ParallelWorkflow[Context any]   = workflow.ParallelWorkflow[Context]
+	SequentialWorkflow[Context any] = workflow.SequentialWorkflow[Context]
+	RouterWorkflow[Context any]     = workflow.RouterWorkflow[Context]
+	GroupRun                        = runner.GroupRun
+	GroupResult                     = runner.GroupResult
+	GroupFunc                       = runner.GroupFunc
+)
+
+// Errors reported by workflows.
+var (
+	ErrNoSteps      = workflow.ErrNoSteps
+	ErrUnknownRoute = workflow.ErrUnknownRoute
+)
+
+// Step makes agent a workflow step.
+func Step[Context any](agent Agent[Context]) Runnable[Context] {
+	return workflow.Agent(agent)
+}
+
+// Parallel returns a workflow running steps concurrently on its input and
+// combining their results.
+func Parallel[Context any](name string, steps ...Runnable[Context]) *ParallelWorkflow[Context] {
+	return workflow.Parallel(name, steps...)
+}
+
+// Sequential returns a workflow passing each step's output to the next one.
+func Sequential[Context any](name string, steps ...Runnable[Context]) *SequentialWorkflow[Context] {
+	return workflow.Sequential(name, steps...)
+}
+
+// Router returns a workflow handing its input to the route picked by
+// classify.
+func Router[Context any](name string, classify Classifier[Context], routes ...Runnable[Context]) *RouterWorkflow[Context] {
+	return workflow.Router(name, classify, routes...)
+}
+
+// ClassifyWith uses agent as a Router's classifier.
+func ClassifyWith[Context any](agent Agent[Context]) Classifier[Context] {
+	return workflow.ClassifyWith(agent)
+}
+
+// JoinOutputs is the default Reducer of a parallel workflow.
+func JoinOutputs(results []WorkflowResult) (string, error) {
+	return workflow.JoinOutputs(results)
+}
+
+// Group reports the runs started by fn as a single run named name, for
+// workflows beyond the built-in ones.
+func Group(runCtx context.Context, name string, input Input, fn GroupFunc, opts ...RunOption) (*AgentResponse, error) {
+	return runner.Group(runCtx, name, input, fn, opts...)
+}
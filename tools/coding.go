@@ -102,9 +102,11 @@ func (f filewrite) Run() any {
 }
 
 var FileWriteTool = tools.BaseTool{
-	Args:        filewrite{},
-	Description: "Writes text to a file.",
-	Name:        "file_write",
+	Args:          filewrite{},
+	Description:   "Writes text to a file.",
+	Name:          "file_write",
+	Sequential:    true,
+	NeedsApproval: true,
 }
 
 type patch struct {
@@ -140,9 +142,11 @@ func (p patch) Run() any {
 }
 
 var PatchTool = tools.BaseTool{
-	Args:        patch{},
-	Description: "Edit a file by doing a text replacement.",
-	Name:        "edit_file",
+	Args:          patch{},
+	Description:   "Edit a file by doing a text replacement.",
+	Name:          "edit_file",
+	Sequential:    true,
+	NeedsApproval: true,
 }
 
 type glob struct {
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -45,6 +46,12 @@ type SearchResponse struct {
 // Optional environment variables:
 //   - GOOGLE_SEARCH_ENDPOINT: Custom API endpoint (defaults to Google's API)
 func (w webSearch) Run() any {
+	return w.RunWithContext(context.Background())
+}
+
+// RunWithContext performs the search, aborting the request when runCtx is
+// cancelled.
+func (w webSearch) RunWithContext(runCtx context.Context) any {
 	// Validate query
 	query := strings.TrimSpace(w.Query)
 	if query == "" {
@@ -81,7 +88,11 @@ func (w webSearch) Run() any {
 	reqURL := endpoint + "?" + params.Encode()
 
 	// Make HTTP request
-	resp, err := http.Get(reqURL)
+	req, err := http.NewRequestWithContext(runCtx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return SearchResponse{Error: fmt.Sprintf("request failed: %v", err)}
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return SearchResponse{Error: fmt.Sprintf("request failed: %v", err)}
 	}